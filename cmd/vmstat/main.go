@@ -84,6 +84,9 @@ type Data struct {
 	swap *mem.Swap
 	virt *mem.Virtual
 	cpu  *cpu.Times
+	load *cpu.LoadAverage    // nil where unsupported
+	rq   *cpu.RunQueueStats  // nil where unsupported
+	sys  *cpu.SystemStats    // nil where unsupported
 }
 
 func CollectData(freq time.Duration) (chan *Data, chan bool) {
@@ -109,10 +112,18 @@ func CollectData(freq time.Duration) (chan *Data, chan bool) {
 					log.Fatal(err)
 				}
 
+				// Not available on every system, the columns stay at 0.
+				l, _ := cpu.LoadAvg()
+				rq, _ := cpu.RunQueue()
+				ss, _ := cpu.Stats()
+
 				DataChan <- &Data{
 					swap : s,
 					virt : v,
 					cpu  : c,
+					load : l,
+					rq   : rq,
+					sys  : ss,
 				}
 			case <- Quit:
 				return
@@ -126,7 +137,7 @@ func CollectData(freq time.Duration) (chan *Data, chan bool) {
 var (
 	header1 = "procs -----------memory----------- ----swap---- -----io---- --system-- ------cpu------\n"
 	header2 = " r  b   swpd   free   buff  cache    si     so    bi    bo    in   cs   us  sy  id  wa \n"
-	line    = " 0  0 %6s %6s %6d %6d %5d %6d %5d %5d %5d %4d %4.0f%4.0f%4.0f%4.0f \n"
+	line    = "%2d %2d %6s %6s %6d %6d %5d %6d %5d %5d %5d %4d %4.0f%4.0f%4.0f%4.0f \n"
 )
 
 
@@ -134,11 +145,15 @@ var (
 func main() {
 	DataChan, QuitChan := CollectData(time.Second)
 
-	fmt.Println()
-	fmt.Printf(header1)
-	fmt.Printf(header2)
 	go func() {
 		prevData := <- DataChan
+
+		fmt.Println()
+		if l := prevData.load; l != nil {
+			fmt.Printf("load average: %.2f, %.2f, %.2f\n", l.Load1, l.Load5, l.Load15)
+		}
+		fmt.Print(header1)
+		fmt.Print(header2)
 		for {
 			data := <- DataChan
 
@@ -150,7 +165,21 @@ func main() {
 
 			tot := user + sys + idle
 
+			var r, b int
+			if data.rq != nil {
+				r, b = data.rq.Running, data.rq.Blocked
+			}
+
+			// Samples are a second apart.
+			var in, cs uint64
+			if data.sys != nil && prevData.sys != nil {
+				in = data.sys.Interrupts - prevData.sys.Interrupts
+				cs = data.sys.ContextSwitches - prevData.sys.ContextSwitches
+			}
+
 			fmt.Printf(line, 
+				       r,
+				       b,
 				       data.virt.Used, 
 				       data.virt.Free, 
 				       0, 
@@ -159,8 +188,8 @@ func main() {
 				       so,
 				       0,
 				       0,
-				       0,
-				       0,
+				       in,
+				       cs,
 				       float64(user) / float64(tot) * 100,
 				       float64(sys) / float64(tot) * 100,
 				       float64(idle) / float64(tot) * 100,
@@ -23,6 +23,63 @@ type Info struct {
 	Flags      []string `json:"flags"`
 }
 
+// Frequency of a logical CPU, in MHz. Min, Max, Governor and Driver are only
+// known when the system exposes frequency scaling.
+type Frequency struct {
+	CPU      int32   `json:"cpu"`
+	Current  float64 `json:"current"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Governor string  `json:"governor"` // scaling governor, such as "powersave" or "performance"
+	Driver   string  `json:"driver"`   // scaling driver, such as "intel_pstate" or "acpi-cpufreq"
+}
+
+type LoadAverage struct {
+	Load1   float64 `json:"load1"`   // average number of runnable or uninterruptible tasks over 1 minute
+	Load5   float64 `json:"load5"`
+	Load15  float64 `json:"load15"`
+	Running int     `json:"running"` // runnable tasks, threads included
+	Total   int     `json:"total"`   // existing tasks, threads included
+	LastPid int     `json:"lastPid"` // pid of the most recently created task
+}
+
+type RunQueueStats struct {
+	Running int `json:"running"` // tasks running or waiting for a CPU
+	Blocked int `json:"blocked"` // tasks blocked waiting for I/O
+}
+
+// SystemStats holds counters since boot.
+type SystemStats struct {
+	Interrupts      uint64 `json:"interrupts"`
+	ContextSwitches uint64 `json:"contextSwitches"`
+	Forks           uint64 `json:"forks"`           // processes and threads created
+	SoftInterrupts  uint64 `json:"softInterrupts"`
+}
+
+// IRQ holds the interrupt counts of an interrupt line since boot.
+type IRQ struct {
+	IRQ         string   `json:"irq"`         // number, or a name such as "NMI" or "LOC"
+	Counts      []uint64 `json:"counts"`      // per CPU, a single total for some names
+	Chip        string   `json:"chip"`        // interrupt controller, numbered lines only
+	Device      string   `json:"device"`      // names of the handlers, comma separated, numbered lines only
+	Description string   `json:"description"` // everything after the counts
+}
+
+// Total returns the sum of the counts of all CPUs.
+func (irq IRQ) Total() uint64 {
+	var t uint64
+	for _, c := range irq.Counts {
+		t += c
+	}
+	return t
+}
+
+// SoftIRQ holds the counts of a kind of software interrupt since boot.
+type SoftIRQ struct {
+	Name   string   `json:"name"`   // such as "TIMER", "NET_RX" or "BLOCK"
+	Counts []uint64 `json:"counts"` // per CPU
+}
+
 func Cores() int {
 	return runtime.NumCPU()
 }
@@ -35,6 +92,36 @@ func GetInfo() ([]Info, error) {
 	return getInfo()
 }
 
+// Frequencies returns the current frequency of each logical CPU.
+func Frequencies() ([]Frequency, error) {
+	return frequencies()
+}
+
+// LoadAvg returns the system load averages.
+func LoadAvg() (*LoadAverage, error) {
+	return loadAvg()
+}
+
+// RunQueue returns the number of runnable and blocked tasks.
+func RunQueue() (*RunQueueStats, error) {
+	return runQueue()
+}
+
+// Stats returns system wide interrupt, context switch and fork counters.
+func Stats() (*SystemStats, error) {
+	return stats()
+}
+
+// Interrupts returns the per CPU counts of each interrupt line.
+func Interrupts() ([]IRQ, error) {
+	return interrupts()
+}
+
+// SoftIRQs returns the per CPU counts of each kind of software interrupt.
+func SoftIRQs() ([]SoftIRQ, error) {
+	return softIRQs()
+}
+
 func SystemTimes() (*Times, error) {
 	return systemTimes()
 }
@@ -0,0 +1,525 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+type Times struct {
+	User      time.Duration `json:"user"`      // includes Guest
+	System    time.Duration `json:"system"`
+	Idle      time.Duration `json:"idle"`
+	Nice      time.Duration `json:"nice"`      // includes GuestNice
+	Iowait    time.Duration `json:"iowait"`
+	Irq       time.Duration `json:"irq"`
+	Softirq   time.Duration `json:"softirq"`
+	Steal     time.Duration `json:"steal"`
+	Guest     time.Duration `json:"guest"`
+	GuestNice time.Duration `json:"guestNice"`
+	Kernel    time.Duration `json:"kernel"`    // System, Irq and Softirq
+}
+
+// total returns the time accounted, guest time is already in User and Nice.
+func (t Times) total() time.Duration {
+	return t.User + t.Nice + t.System + t.Idle + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+func getInfo() ([]Info, error) {
+	lines, err := linux.ReadLines(linux.ProcPath("cpuinfo"))
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUInfo(lines), nil
+}
+
+// parseCPUInfo parses the blocks of /proc/cpuinfo, one per logical CPU.
+// Architectures other than x86 leave most fields empty.
+func parseCPUInfo(lines []string) []Info {
+	var (
+		ret []Info
+		cur *Info
+	)
+
+	for _, l := range lines {
+		i := strings.IndexByte(l, ':')
+		if i < 0 {
+			continue
+		}
+		key := strings.TrimSpace(l[:i])
+		value := strings.TrimSpace(l[i+1:])
+
+		if key == "processor" {
+			n, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				// The summary line of some ARM kernels, "Processor : ARMv7 ...".
+				continue
+			}
+			ret = append(ret, Info{CPU: int32(n), Flags: []string{}})
+			cur = &ret[len(ret)-1]
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		switch key {
+		case "vendor_id":
+			cur.VendorId = value
+		case "cpu family":
+			cur.Family = value
+		case "model":
+			cur.Model = value
+		case "model name":
+			cur.ModelName = value
+		case "stepping":
+			n, _ := strconv.ParseInt(value, 10, 32)
+			cur.Stepping = int32(n)
+		case "physical id":
+			cur.PhysicalId = value
+		case "core id":
+			cur.CoreId = value
+		case "cpu cores":
+			n, _ := strconv.ParseInt(value, 10, 32)
+			cur.Cores = int32(n)
+		case "cpu MHz":
+			cur.Mhz, _ = strconv.ParseFloat(value, 64)
+		case "cache size":
+			n, _ := strconv.ParseInt(strings.TrimSuffix(value, " KB"), 10, 32)
+			cur.CacheSize = int32(n)
+		case "flags", "Features":
+			cur.Flags = strings.Fields(value)
+		}
+	}
+	return ret
+}
+
+// Retrieves system CPU timing information from /proc/stat, summed across all
+// processors.
+func systemTimes() (*Times, error) {
+	times, err := readStatTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := times["cpu"]
+	if !ok {
+		return nil, fmt.Errorf("cpu line not found in %s", linux.ProcPath("stat"))
+	}
+	return t, nil
+}
+
+// readStatTimes reads the cpu lines of /proc/stat, the total under "cpu"
+// and every processor under "cpuN".
+func readStatTimes() (map[string]*Times, error) {
+	lines, err := linux.ReadLines(linux.ProcPath("stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]*Times)
+	for _, l := range lines {
+		if !strings.HasPrefix(l, "cpu") {
+			continue
+		}
+		f := strings.Fields(l)
+
+		var ticks [10]uint64
+		for i := 1; i < len(f) && i <= len(ticks); i++ {
+			ticks[i-1], _ = strconv.ParseUint(f[i], 10, 64)
+		}
+
+		t := &Times{
+			User      : linux.TicksToDuration(ticks[0]),
+			Nice      : linux.TicksToDuration(ticks[1]),
+			System    : linux.TicksToDuration(ticks[2]),
+			Idle      : linux.TicksToDuration(ticks[3]),
+			Iowait    : linux.TicksToDuration(ticks[4]),
+			Irq       : linux.TicksToDuration(ticks[5]),
+			Softirq   : linux.TicksToDuration(ticks[6]),
+			Steal     : linux.TicksToDuration(ticks[7]),
+			Guest     : linux.TicksToDuration(ticks[8]),
+			GuestNice : linux.TicksToDuration(ticks[9]),
+		}
+		t.Kernel = t.System + t.Irq + t.Softirq
+		ret[f[0]] = t
+	}
+	return ret, nil
+}
+
+var (
+	usageMu   sync.Mutex
+	lastTimes map[string]*Times
+)
+
+// The utilization of each processor as a percentage, since the previous
+// call, or since boot for the first one.
+func usagePercent() ([]float64, error) {
+	times, err := readStatTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	usageMu.Lock()
+	prev := lastTimes
+	lastTimes = times
+	usageMu.Unlock()
+
+	var ret []float64
+	for i := 0; ; i++ {
+		name := "cpu" + strconv.Itoa(i)
+		cur, ok := times[name]
+		if !ok {
+			break
+		}
+
+		total := cur.total()
+		idle := cur.Idle + cur.Iowait
+		if p, ok := prev[name]; ok {
+			total -= p.total()
+			idle -= p.Idle + p.Iowait
+		}
+
+		var percent float64
+		if total > 0 {
+			percent = float64(total - idle) / float64(total) * 100
+		}
+		ret = append(ret, percent)
+	}
+	return ret, nil
+}
+
+// loadAvg parses /proc/loadavg, "0.06 0.19 0.15 1/72 9182".
+func loadAvg() (*LoadAverage, error) {
+	s, err := linux.ReadString(linux.ProcPath("loadavg"))
+	if err != nil {
+		return nil, err
+	}
+	return parseLoadAvg(s)
+}
+
+func parseLoadAvg(s string) (*LoadAverage, error) {
+	f := strings.Fields(s)
+	if len(f) != 5 {
+		return nil, fmt.Errorf("malformed loadavg: %q", s)
+	}
+	tasks := strings.SplitN(f[3], "/", 2)
+	if len(tasks) != 2 {
+		return nil, fmt.Errorf("malformed loadavg: %q", s)
+	}
+
+	var (
+		la  LoadAverage
+		err error
+	)
+	parseFloat := func(s string) float64 {
+		v, e := strconv.ParseFloat(s, 64)
+		if e != nil {
+			err = e
+		}
+		return v
+	}
+	parseInt := func(s string) int {
+		v, e := strconv.Atoi(s)
+		if e != nil {
+			err = e
+		}
+		return v
+	}
+
+	la.Load1 = parseFloat(f[0])
+	la.Load5 = parseFloat(f[1])
+	la.Load15 = parseFloat(f[2])
+	la.Running = parseInt(tasks[0])
+	la.Total = parseInt(tasks[1])
+	la.LastPid = parseInt(f[4])
+	if err != nil {
+		return nil, fmt.Errorf("malformed loadavg: %q", s)
+	}
+	return &la, nil
+}
+
+// runQueue reads the procs_running and procs_blocked lines of /proc/stat.
+func runQueue() (*RunQueueStats, error) {
+	lines, err := linux.ReadLines(linux.ProcPath("stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	rq := &RunQueueStats{}
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) != 2 {
+			continue
+		}
+		switch f[0] {
+		case "procs_running":
+			rq.Running, _ = strconv.Atoi(f[1])
+		case "procs_blocked":
+			rq.Blocked, _ = strconv.Atoi(f[1])
+		}
+	}
+	return rq, nil
+}
+
+// stats reads the counters of /proc/stat.
+func stats() (*SystemStats, error) {
+	lines, err := linux.ReadLines(linux.ProcPath("stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &SystemStats{}
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) < 2 {
+			continue
+		}
+
+		// intr and softirq are followed by the per source counts.
+		switch f[0] {
+		case "intr":
+			ss.Interrupts, _ = strconv.ParseUint(f[1], 10, 64)
+		case "ctxt":
+			ss.ContextSwitches, _ = strconv.ParseUint(f[1], 10, 64)
+		case "processes":
+			ss.Forks, _ = strconv.ParseUint(f[1], 10, 64)
+		case "softirq":
+			ss.SoftInterrupts, _ = strconv.ParseUint(f[1], 10, 64)
+		}
+	}
+	return ss, nil
+}
+
+func interrupts() ([]IRQ, error) {
+	lines, err := linux.ReadLines(linux.ProcPath("interrupts"))
+	if err != nil {
+		return nil, err
+	}
+	return parseInterrupts(lines)
+}
+
+// parseInterrupts parses /proc/interrupts: a header naming the online CPUs,
+// then a line per interrupt, " 24:   1   0  IO-APIC   5-edge   ACPI:Ged".
+func parseInterrupts(lines []string) ([]IRQ, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	ncpu := len(strings.Fields(lines[0]))
+
+	var ret []IRQ
+	for _, l := range lines[1:] {
+		i := strings.IndexByte(l, ':')
+		if i < 0 {
+			continue
+		}
+
+		irq := IRQ{IRQ: strings.TrimSpace(l[:i])}
+
+		rest := l[i+1:]
+		for len(irq.Counts) < ncpu {
+			f, tail := nextField(rest)
+			n, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				break
+			}
+			irq.Counts = append(irq.Counts, n)
+			rest = tail
+		}
+		irq.Description = strings.TrimSpace(rest)
+
+		if _, err := strconv.Atoi(irq.IRQ); err == nil {
+			irq.Chip, irq.Device = interruptSource(irq.Description)
+		}
+		ret = append(ret, irq)
+	}
+	return ret, nil
+}
+
+func softIRQs() ([]SoftIRQ, error) {
+	lines, err := linux.ReadLines(linux.ProcPath("softirqs"))
+	if err != nil {
+		return nil, err
+	}
+	return parseSoftIRQs(lines)
+}
+
+// parseSoftIRQs parses /proc/softirqs, a header naming the CPUs then a line
+// per kind, "NET_RX:   2679   310".
+func parseSoftIRQs(lines []string) ([]SoftIRQ, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	var ret []SoftIRQ
+	for _, l := range lines[1:] {
+		f := strings.Fields(l)
+		if len(f) == 0 || !strings.HasSuffix(f[0], ":") {
+			continue
+		}
+
+		si := SoftIRQ{Name: strings.TrimSuffix(f[0], ":")}
+		for _, v := range f[1:] {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed softirqs: %q", l)
+			}
+			si.Counts = append(si.Counts, n)
+		}
+		ret = append(ret, si)
+	}
+	return ret, nil
+}
+
+// nextField returns the first space separated field of s and what follows.
+func nextField(s string) (string, string) {
+	s = strings.TrimLeft(s, " ")
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// interruptSource splits the description of a numbered interrupt into the
+// chip name and the handlers following the trigger field, as in
+// "IR-PCI-MSI 1048576-edge      eth0-TxRx-0" or "GICv3  27 Level  arch_timer".
+func interruptSource(desc string) (chip, device string) {
+	f := strings.Fields(desc)
+	if len(f) == 0 {
+		return "", ""
+	}
+	chip = f[0]
+
+	for i, v := range f[1:] {
+		if isTrigger(v) {
+			rest := desc
+			for j := 0; j <= i+1; j++ {
+				_, rest = nextField(rest)
+			}
+			return chip, strings.TrimSpace(rest)
+		}
+	}
+	return chip, ""
+}
+
+// isTrigger reports whether f is the trigger type column of /proc/interrupts.
+func isTrigger(f string) bool {
+	switch {
+	case f == "Edge", f == "Level":
+		return true
+	case strings.HasSuffix(f, "-edge"), strings.HasSuffix(f, "-level"),
+		strings.HasSuffix(f, "-fasteoi"):
+		return true
+	}
+	return false
+}
+
+//---------------------------------------------------------------------------------------
+
+// frequencies reads cpufreq from sysfs, CPUs without it get the frequency
+// of /proc/cpuinfo.
+func frequencies() ([]Frequency, error) {
+	cpus, err := onlineCPUs()
+	if err != nil {
+		return nil, err
+	}
+
+	var info map[int32]float64
+
+	var ret []Frequency
+	for _, cpu := range cpus {
+		f := Frequency{CPU: cpu}
+		if readCPUFreq(&f) {
+			ret = append(ret, f)
+			continue
+		}
+
+		if info == nil {
+			info = make(map[int32]float64)
+			all, err := getInfo()
+			if err != nil {
+				return nil, err
+			}
+			for _, i := range all {
+				info[i.CPU] = i.Mhz
+			}
+		}
+		f.Current = info[cpu]
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+// readCPUFreq fills f from /sys/devices/system/cpu/cpuN/cpufreq, it returns
+// false when the CPU has no cpufreq driver.
+func readCPUFreq(f *Frequency) bool {
+	dir := linux.SysPath("devices", "system", "cpu", fmt.Sprintf("cpu%d", f.CPU), "cpufreq")
+
+	// The values are in kHz.
+	mhz := func(names ...string) float64 {
+		for _, name := range names {
+			if khz, err := linux.ReadUint(dir + "/" + name); err == nil {
+				return float64(khz) / 1000
+			}
+		}
+		return 0
+	}
+
+	f.Current = mhz("scaling_cur_freq", "cpuinfo_cur_freq")
+	if f.Current == 0 {
+		return false
+	}
+	f.Min = mhz("scaling_min_freq", "cpuinfo_min_freq")
+	f.Max = mhz("scaling_max_freq", "cpuinfo_max_freq")
+	f.Governor, _ = linux.ReadString(dir + "/scaling_governor")
+	f.Driver, _ = linux.ReadString(dir + "/scaling_driver")
+	return true
+}
+
+// onlineCPUs lists the logical CPUs of /sys/devices/system/cpu that are
+// online.
+func onlineCPUs() ([]int32, error) {
+	dir := linux.SysPath("devices", "system", "cpu")
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []int32
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "cpu") {
+			continue
+		}
+		n, err := strconv.ParseInt(name[3:], 10, 32)
+		if err != nil {
+			continue
+		}
+
+		// cpu0 usually has no online file, it cannot be taken offline.
+		online, err := linux.ReadUint(dir + "/" + name + "/online")
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil && online == 0 {
+			continue
+		}
+		ret = append(ret, int32(n))
+	}
+
+	// ReadDir sorts by name, cpu10 comes before cpu2.
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/entuerto/sysmon"
+	"github.com/entuerto/sysmon/internal/linux/linuxtest"
+)
+
+func TestFrequencies(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_cur_freq": "2400000\n",
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_min_freq": "800000\n",
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_max_freq": "3600000\n",
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_governor": "powersave\n",
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_driver":   "intel_pstate\n",
+		"sys/devices/system/cpu/cpu1/online":                   "0\n",
+		"sys/devices/system/cpu/cpu10/online":                  "1\n",
+		"sys/devices/system/cpu/cpufreq/policy0/scaling_cur_freq": "2400000\n",
+		"proc/cpuinfo": "processor\t: 0\ncpu MHz\t\t: 2400.000\n\nprocessor\t: 10\ncpu MHz\t\t: 1999.998\n",
+	})()
+
+	freqs, err := Frequencies()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(freqs) != 2 {
+		t.Fatalf("got %d frequencies, want 2: %v", len(freqs), freqs)
+	}
+
+	want := Frequency{CPU: 0, Current: 2400, Min: 800, Max: 3600, Governor: "powersave", Driver: "intel_pstate"}
+	if freqs[0] != want {
+		t.Errorf("got %v, want %v", freqs[0], want)
+	}
+
+	// No cpufreq, from /proc/cpuinfo.
+	want = Frequency{CPU: 10, Current: 1999.998}
+	if freqs[1] != want {
+		t.Errorf("got %v, want %v", freqs[1], want)
+	}
+}
+
+func TestLoadAvg(t *testing.T) {
+	la, err := parseLoadAvg("0.06 0.19 1.50 3/72 9182\n")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	want := LoadAverage{Load1: 0.06, Load5: 0.19, Load15: 1.5, Running: 3, Total: 72, LastPid: 9182}
+	if *la != want {
+		t.Errorf("got %v, want %v", *la, want)
+	}
+
+	if _, err := parseLoadAvg("0.06 0.19 1.50 72 9182"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestRunQueue(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{
+		"proc/stat": "cpu  16850 0 2818 123831 5720 0 2 155 0 0\nctxt 787537\nprocs_running 2\nprocs_blocked 1\n",
+	})()
+
+	rq, err := RunQueue()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if rq.Running != 2 || rq.Blocked != 1 {
+		t.Errorf("got %v", *rq)
+	}
+}
+
+func TestStats(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{
+		"proc/stat": "cpu  16850 0 2818 123831 5720 0 2 155 0 0\nintr 393872 0 0 1\nctxt 855144\nbtime 1792426361\nprocesses 10006\nsoftirq 66179 0 30256\n",
+	})()
+
+	ss, err := Stats()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	want := SystemStats{Interrupts: 393872, ContextSwitches: 855144, Forks: 10006, SoftInterrupts: 66179}
+	if *ss != want {
+		t.Errorf("got %v, want %v", *ss, want)
+	}
+}
+
+func TestParseInterrupts(t *testing.T) {
+	irqs, err := parseInterrupts([]string{
+		"           CPU0       CPU1       ",
+		"  0:         44          0   IO-APIC   2-edge      timer",
+		" 28:          0        512 PCI-MSIX-0000:00:01.0   0-edge      eth0-rx-0, eth0",
+		" 29:          3          0  GICv3  27 Level",
+		" 30:          0          5  IR-PCI-MSI 1048576-edge      eth0-TxRx-0",
+		"NMI:          2          1   Non-maskable interrupts",
+		"ERR:          0",
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(irqs) != 6 {
+		t.Fatalf("got %d irqs, want 6", len(irqs))
+	}
+
+	if irq := irqs[0]; irq.Chip != "IO-APIC" || irq.Device != "timer" {
+		t.Errorf("irq = %+v", irq)
+	}
+	if irq := irqs[1]; irq.IRQ != "28" || irq.Total() != 512 || irq.Counts[1] != 512 || irq.Chip != "PCI-MSIX-0000:00:01.0" || irq.Device != "eth0-rx-0, eth0" {
+		t.Errorf("irq = %+v", irq)
+	}
+	if irq := irqs[2]; irq.Chip != "GICv3" || irq.Device != "" {
+		t.Errorf("irq = %+v", irq)
+	}
+	if irq := irqs[3]; irq.IRQ != "30" || irq.Chip != "IR-PCI-MSI" || irq.Device != "eth0-TxRx-0" {
+		t.Errorf("irq = %+v", irq)
+	}
+	if irq := irqs[4]; irq.IRQ != "NMI" || len(irq.Counts) != 2 || irq.Description != "Non-maskable interrupts" || irq.Chip != "" {
+		t.Errorf("irq = %+v", irq)
+	}
+	if irq := irqs[5]; len(irq.Counts) != 1 {
+		t.Errorf("irq = %+v", irq)
+	}
+}
+
+func TestParseSoftIRQs(t *testing.T) {
+	sirqs, err := parseSoftIRQs([]string{
+		"                    CPU0       CPU1",
+		"          HI:          0          1",
+		"      NET_RX:       2679         12",
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(sirqs) != 2 || sirqs[1].Name != "NET_RX" || len(sirqs[1].Counts) != 2 || sirqs[1].Counts[0] != 2679 {
+		t.Errorf("softirqs = %+v", sirqs)
+	}
+}
+
+func TestTopology(t *testing.T) {
+	files := map[string]string{
+		"sys/devices/system/node/node0/cpulist":  "0-3\n",
+		"sys/devices/system/node/node0/distance": "10\n",
+	}
+	// Two cores of two threads, cpu0 and cpu2 share the first core.
+	for cpu, core := range []string{"0", "1", "0", "1"} {
+		dir := "sys/devices/system/cpu/cpu" + strconv.Itoa(cpu)
+		siblings := map[string]string{"0": "0,2", "1": "1,3"}[core]
+
+		files[dir + "/topology/physical_package_id"] = "0\n"
+		files[dir + "/topology/die_id"] = "0\n"
+		files[dir + "/topology/core_id"] = core + "\n"
+		files[dir + "/cache/index0/level"] = "1\n"
+		files[dir + "/cache/index0/type"] = "Data\n"
+		files[dir + "/cache/index0/size"] = "48K\n"
+		files[dir + "/cache/index0/coherency_line_size"] = "64\n"
+		files[dir + "/cache/index0/shared_cpu_list"] = siblings + "\n"
+		files[dir + "/cache/index3/level"] = "3\n"
+		files[dir + "/cache/index3/type"] = "Unified\n"
+		files[dir + "/cache/index3/size"] = "32768K\n"
+		files[dir + "/cache/index3/shared_cpu_list"] = "0-3\n"
+	}
+	defer linuxtest.FakeTree(t, files)()
+
+	topo, err := Topology()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if len(topo.Packages) != 1 || len(topo.Packages[0].Dies) != 1 {
+		t.Fatalf("topology = %+v", topo)
+	}
+	cores := topo.Packages[0].Dies[0].Cores
+	if len(cores) != 2 || len(cores[0].Threads) != 2 || cores[0].Threads[1].CPU != 2 || cores[0].Threads[1].Node != 0 {
+		t.Fatalf("cores = %+v", cores)
+	}
+
+	if len(topo.Caches) != 3 {
+		t.Fatalf("got %d caches, want 3", len(topo.Caches))
+	}
+	l3 := topo.Caches[2]
+	if l3.Name() != "L3" || l3.Size != 32 * sysmon.MB || len(l3.SharedCPUs) != 4 {
+		t.Errorf("L3 = %#v", l3)
+	}
+	if cores[0].Caches[1] != l3 || cores[1].Caches[1] != l3 {
+		t.Error("L3 not shared by the cores")
+	}
+	if c := cores[1].Caches[0]; c.Name() != "L1d" || c.Size != 48 * sysmon.KB || c.LineSize != 64 || c.SharedCPUs[1] != 3 {
+		t.Errorf("L1d = %#v", c)
+	}
+
+	if len(topo.Nodes) != 1 || len(topo.Nodes[0].CPUs) != 4 || topo.Nodes[0].Distances[0] != 10 {
+		t.Errorf("nodes = %+v", topo.Nodes)
+	}
+}
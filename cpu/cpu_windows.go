@@ -5,7 +5,9 @@
 package cpu
 
 import (
+	"errors"
 	"fmt"
+	"runtime"
 	"syscall"
 	"time"
 
@@ -105,4 +107,48 @@ func usagePercent() ([]float64, error) {
 func fileTimeToDuration(ft syscall.Filetime) time.Duration {
 	n := int64(ft.HighDateTime) << 32 + int64(ft.LowDateTime) // in 100-nanosecond intervals
 	return time.Duration(n * 100) * time.Nanosecond
-}
\ No newline at end of file
+}
+
+// The frequencies come from the power manager, which has neither a minimum
+// nor a governor.
+func frequencies() ([]Frequency, error) {
+	infos, err := win32.CallNtPowerInformationProcessors(runtime.NumCPU())
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Frequency
+	for _, i := range infos {
+		if i.MaxMhz == 0 {
+			// Unused entries of a buffer larger than needed.
+			continue
+		}
+		ret = append(ret, Frequency{
+			CPU     : int32(i.Number),
+			Current : float64(i.CurrentMhz),
+			Max     : float64(i.MaxMhz),
+		})
+	}
+	return ret, nil
+}
+
+// Windows keeps no load average.
+func loadAvg() (*LoadAverage, error) {
+	return nil, errors.New("load average not supported on windows")
+}
+
+func runQueue() (*RunQueueStats, error) {
+	return nil, errors.New("run queue not supported on windows")
+}
+
+func stats() (*SystemStats, error) {
+	return nil, errors.New("system stats not supported on windows")
+}
+
+func interrupts() ([]IRQ, error) {
+	return nil, errors.New("interrupts not supported on windows")
+}
+
+func softIRQs() ([]SoftIRQ, error) {
+	return nil, errors.New("softirqs not supported on windows")
+}
@@ -0,0 +1,303 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/entuerto/sysmon"
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// TopologyInfo describes how the logical CPUs are laid out: packages
+// (sockets) hold dies, dies hold cores and cores hold hardware threads.
+type TopologyInfo struct {
+	Packages []*Package  `json:"packages"`
+	Nodes    []*NUMANode `json:"nodes"`  // empty without NUMA support
+	Caches   []*Cache    `json:"caches"` // every cache once, by level
+}
+
+type Package struct {
+	ID   int    `json:"id"`
+	Dies []*Die `json:"dies"`
+}
+
+type Die struct {
+	ID    int     `json:"id"`
+	Cores []*Core `json:"cores"`
+}
+
+type Core struct {
+	ID      int           `json:"id"`      // unique within the die only
+	Threads []*LogicalCPU `json:"threads"`
+	Caches  []*Cache      `json:"caches"`  // the caches the core uses, from L1 up
+}
+
+type LogicalCPU struct {
+	CPU  int `json:"cpu"`
+	Node int `json:"node"` // NUMA node, -1 when unknown
+}
+
+type Cache struct {
+	Level      int         `json:"level"`
+	Type       string      `json:"type"`       // "Data", "Instruction" or "Unified"
+	Size       sysmon.Size `json:"size"`
+	LineSize   int         `json:"lineSize"`   // coherency line size in bytes
+	Ways       int         `json:"ways"`       // associativity, 0 for fully associative
+	SharedCPUs []int       `json:"sharedCpus"` // logical CPUs sharing the cache
+}
+
+// Name returns the usual name of the cache, such as "L1d" or "L3".
+func (c Cache) Name() string {
+	name := fmt.Sprintf("L%d", c.Level)
+	switch c.Type {
+	case "Data":
+		name += "d"
+	case "Instruction":
+		name += "i"
+	}
+	return name
+}
+
+func (c Cache) GoString() string {
+	s := []string{"Cache{",
+			fmt.Sprintf("  Name       : %s", c.Name()),
+			fmt.Sprintf("  Size       : %s", c.Size),
+			fmt.Sprintf("  LineSize   : %d", c.LineSize),
+			fmt.Sprintf("  Ways       : %d", c.Ways),
+			fmt.Sprintf("  SharedCPUs : %v", c.SharedCPUs),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+type NUMANode struct {
+	ID        int   `json:"id"`
+	CPUs      []int `json:"cpus"`
+	Distances []int `json:"distances"` // relative access cost to each node, by node id order
+}
+
+// Topology returns the layout of the online CPUs, from
+// /sys/devices/system/cpu and /sys/devices/system/node.
+func Topology() (*TopologyInfo, error) {
+	cpus, err := onlineCPUs()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := numaNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeOf := make(map[int]int)
+	for _, n := range nodes {
+		for _, cpu := range n.CPUs {
+			nodeOf[cpu] = n.ID
+		}
+	}
+
+	topo := &TopologyInfo{Nodes: nodes}
+	packages := make(map[int]*Package)
+	dies := make(map[[2]int]*Die)
+	cores := make(map[[3]int]*Core)
+	caches := make(map[string]*Cache)
+
+	for _, cpu := range cpus {
+		dir := linux.SysPath("devices", "system", "cpu", fmt.Sprintf("cpu%d", cpu))
+
+		pkgID := readInt(dir + "/topology/physical_package_id")
+		dieID := readInt(dir + "/topology/die_id")
+		coreID := readInt(dir + "/topology/core_id")
+
+		pkg, ok := packages[pkgID]
+		if !ok {
+			pkg = &Package{ID: pkgID}
+			packages[pkgID] = pkg
+			topo.Packages = append(topo.Packages, pkg)
+		}
+
+		die, ok := dies[[2]int{pkgID, dieID}]
+		if !ok {
+			die = &Die{ID: dieID}
+			dies[[2]int{pkgID, dieID}] = die
+			pkg.Dies = append(pkg.Dies, die)
+		}
+
+		core, ok := cores[[3]int{pkgID, dieID, coreID}]
+		if !ok {
+			core = &Core{ID: coreID}
+			cores[[3]int{pkgID, dieID, coreID}] = core
+			die.Cores = append(die.Cores, core)
+
+			cs, err := readCaches(dir + "/cache", caches)
+			if err != nil {
+				return nil, err
+			}
+			core.Caches = cs
+		}
+
+		node, ok := nodeOf[int(cpu)]
+		if !ok {
+			node = -1
+		}
+		core.Threads = append(core.Threads, &LogicalCPU{CPU: int(cpu), Node: node})
+	}
+
+	sort.Slice(topo.Packages, func(i, j int) bool { return topo.Packages[i].ID < topo.Packages[j].ID })
+	for _, pkg := range topo.Packages {
+		sort.Slice(pkg.Dies, func(i, j int) bool { return pkg.Dies[i].ID < pkg.Dies[j].ID })
+		for _, die := range pkg.Dies {
+			sort.Slice(die.Cores, func(i, j int) bool { return die.Cores[i].ID < die.Cores[j].ID })
+		}
+	}
+
+	for _, c := range caches {
+		topo.Caches = append(topo.Caches, c)
+	}
+	sort.Slice(topo.Caches, func(i, j int) bool {
+		ci, cj := topo.Caches[i], topo.Caches[j]
+		if ci.Level != cj.Level {
+			return ci.Level < cj.Level
+		}
+		if ci.Type != cj.Type {
+			return ci.Type < cj.Type
+		}
+		return ci.SharedCPUs[0] < cj.SharedCPUs[0]
+	})
+	return topo, nil
+}
+
+// readCaches reads the cache/indexN directories of a CPU. Caches shared
+// with CPUs already read are taken from known, so each cache is a single
+// value.
+func readCaches(dir string, known map[string]*Cache) ([]*Cache, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Not exposed by some virtual machines and architectures.
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ret []*Cache
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "index") {
+			continue
+		}
+		idx := dir + "/" + e.Name()
+
+		shared, err := linux.ReadCPUList(idx + "/shared_cpu_list")
+		if err != nil || len(shared) == 0 {
+			continue
+		}
+
+		level := readInt(idx + "/level")
+		typ, _ := linux.ReadString(idx + "/type")
+
+		key := fmt.Sprintf("%d/%s/%v", level, typ, shared)
+		c, ok := known[key]
+		if !ok {
+			size, _ := linux.ReadString(idx + "/size")
+			c = &Cache{
+				Level      : level,
+				Type       : typ,
+				Size       : parseCacheSize(size),
+				LineSize   : readInt(idx + "/coherency_line_size"),
+				Ways       : readInt(idx + "/ways_of_associativity"),
+				SharedCPUs : shared,
+			}
+			known[key] = c
+		}
+		ret = append(ret, c)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Level != ret[j].Level {
+			return ret[i].Level < ret[j].Level
+		}
+		return ret[i].Type < ret[j].Type
+	})
+	return ret, nil
+}
+
+// numaNodes reads /sys/devices/system/node/nodeN, it returns no nodes on
+// kernels without NUMA support.
+func numaNodes() ([]*NUMANode, error) {
+	dir := linux.SysPath("devices", "system", "node")
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ret []*NUMANode
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "node") {
+			continue
+		}
+		id, err := strconv.Atoi(e.Name()[4:])
+		if err != nil {
+			continue
+		}
+
+		cpus, err := linux.ReadCPUList(dir + "/" + e.Name() + "/cpulist")
+		if err != nil {
+			return nil, err
+		}
+
+		var distances []int
+		if s, err := linux.ReadString(dir + "/" + e.Name() + "/distance"); err == nil {
+			for _, f := range strings.Fields(s) {
+				d, _ := strconv.Atoi(f)
+				distances = append(distances, d)
+			}
+		}
+
+		ret = append(ret, &NUMANode{
+			ID        : id,
+			CPUs      : cpus,
+			Distances : distances,
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret, nil
+}
+
+// parseCacheSize parses sizes such as "48K" or "32M".
+func parseCacheSize(s string) sysmon.Size {
+	unit := sysmon.Size(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		unit = sysmon.KB
+	case strings.HasSuffix(s, "M"):
+		unit = sysmon.MB
+	case strings.HasSuffix(s, "G"):
+		unit = sysmon.GB
+	}
+
+	n, _ := strconv.ParseUint(strings.TrimRight(s, "KMG"), 10, 64)
+	return sysmon.Size(n) * unit
+}
+
+// readInt reads a file holding a single integer, 0 when it cannot be read.
+func readInt(path string) int {
+	s, err := linux.ReadString(path)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
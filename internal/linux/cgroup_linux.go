@@ -0,0 +1,211 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CgroupEntry is a line of /proc/<pid>/cgroup. The cgroup v2 unified
+// hierarchy has id 0 and no controllers.
+type CgroupEntry struct {
+	HierarchyID int
+	Controllers []string
+	Path        string
+}
+
+// ReadCgroups parses /proc/<pid>/cgroup.
+func ReadCgroups(path string) ([]CgroupEntry, error) {
+	lines, err := ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCgroups(lines)
+}
+
+// ParseCgroups parses lines of the form hierarchy-ID:controller-list:cgroup-path.
+func ParseCgroups(lines []string) ([]CgroupEntry, error) {
+	var ret []CgroupEntry
+
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+
+		f := strings.SplitN(l, ":", 3)
+		if len(f) != 3 {
+			return nil, fmt.Errorf("malformed cgroup: %q", l)
+		}
+
+		id, err := strconv.Atoi(f[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed cgroup: %q", l)
+		}
+
+		var controllers []string
+		if f[1] != "" {
+			controllers = strings.Split(f[1], ",")
+		}
+
+		ret = append(ret, CgroupEntry{
+			HierarchyID : id,
+			Controllers : controllers,
+			Path        : f[2],
+		})
+	}
+	return ret, nil
+}
+
+// CgroupMount is a mounted cgroup hierarchy.
+type CgroupMount struct {
+	Mountpoint  string
+	Root        string   // cgroup path mounted at Mountpoint, "/" unless in a cgroup namespace or bind mount
+	Version     int      // 1 or 2
+	Controllers []string // cgroup v1 only, "name=systemd" for named hierarchies
+}
+
+// Path returns the file system path of cgroup, or an empty string when the
+// cgroup is not visible under the mount point.
+func (m CgroupMount) Path(cgroup string) string {
+	rel := cgroup
+	if m.Root != "/" {
+		if cgroup != m.Root && !strings.HasPrefix(cgroup, m.Root + "/") {
+			return ""
+		}
+		rel = strings.TrimPrefix(cgroup, m.Root)
+	}
+	return filepath.Join(m.Mountpoint, rel)
+}
+
+// HasController reports whether the cgroup v1 hierarchy has controller.
+func (m CgroupMount) HasController(controller string) bool {
+	for _, c := range m.Controllers {
+		if c == controller {
+			return true
+		}
+	}
+	return false
+}
+
+// Mount options of cgroup v1 that are not controllers.
+var cgroupOptions = map[string]bool{
+	"rw"             : true,
+	"ro"             : true,
+	"none"           : true,
+	"xattr"          : true,
+	"noprefix"       : true,
+	"clone_children" : true,
+	"cpuset_v2_mode" : true,
+}
+
+// ReadCgroupMounts returns the cgroup hierarchies mounted in the mount
+// namespace of the caller, from /proc/self/mountinfo.
+func ReadCgroupMounts() ([]CgroupMount, error) {
+	lines, err := ReadLines(ProcPath("self", "mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCgroupMounts(lines), nil
+}
+
+// ParseCgroupMounts parses the cgroup entries of mountinfo lines:
+// "id parent major:minor root mountpoint options [optional...] - fstype source superoptions".
+func ParseCgroupMounts(lines []string) []CgroupMount {
+	var ret []CgroupMount
+
+	for _, l := range lines {
+		f := strings.Fields(l)
+
+		sep := -1
+		for i := 6; i < len(f); i++ {
+			if f[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || sep+3 >= len(f) {
+			continue
+		}
+
+		m := CgroupMount{
+			Mountpoint : f[4],
+			Root       : f[3],
+		}
+
+		switch f[sep+1] {
+		case "cgroup2":
+			m.Version = 2
+		case "cgroup":
+			m.Version = 1
+			for _, opt := range strings.Split(f[sep+3], ",") {
+				if strings.HasPrefix(opt, "name=") || !cgroupOptions[opt] && !strings.Contains(opt, "=") {
+					m.Controllers = append(m.Controllers, opt)
+				}
+			}
+		default:
+			continue
+		}
+		ret = append(ret, m)
+	}
+	return ret
+}
+
+// Container runtimes recognized by ContainerID.
+const (
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+	RuntimeCRIO       = "cri-o"
+	RuntimePodman     = "podman"
+	RuntimeKubepods   = "kubepods"
+)
+
+var (
+	// docker-<id>.scope, cri-containerd-<id>.scope, crio-<id>.scope, libpod-<id>.scope
+	scopeIDRe = regexp.MustCompile(`^(docker|cri-containerd|crio|libpod)-([0-9a-f]{64})(\.scope)?$`)
+	// /docker/<id>, /kubepods/burstable/pod<uid>/<id>
+	plainIDRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+var scopeRuntimes = map[string]string{
+	"docker"         : RuntimeDocker,
+	"cri-containerd" : RuntimeContainerd,
+	"crio"           : RuntimeCRIO,
+	"libpod"         : RuntimePodman,
+}
+
+// ContainerID extracts the container id and runtime from a cgroup path.
+// Both the cgroupfs ("/docker/<id>") and systemd ("docker-<id>.scope")
+// naming conventions are recognized. It returns empty strings when the path
+// does not belong to a container.
+func ContainerID(path string) (id, runtime string) {
+	elems := strings.Split(path, "/")
+
+	for i := len(elems) - 1; i >= 0; i-- {
+		if m := scopeIDRe.FindStringSubmatch(elems[i]); m != nil {
+			return m[2], scopeRuntimes[m[1]]
+		}
+
+		if !plainIDRe.MatchString(elems[i]) {
+			continue
+		}
+
+		parents := strings.Join(elems[:i], "/")
+		switch {
+		case strings.Contains(parents, "kubepods"):
+			return elems[i], RuntimeKubepods
+		case strings.Contains(parents, "libpod"):
+			return elems[i], RuntimePodman
+		case strings.Contains(parents, "containerd"):
+			return elems[i], RuntimeContainerd
+		default:
+			return elems[i], RuntimeDocker
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCgroups(t *testing.T) {
+	lines := []string{
+		"12:cpu,cpuacct:/docker/0123",
+		"1:name=systemd:/user.slice",
+		"0::/system.slice/sshd.service",
+	}
+
+	cgroups, err := ParseCgroups(lines)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if len(cgroups) != 3 {
+		t.Fatalf("got %d cgroups, want 3", len(cgroups))
+	}
+	if strings.Join(cgroups[0].Controllers, ",") != "cpu,cpuacct" || cgroups[0].Path != "/docker/0123" {
+		t.Errorf("cgroup v1 = %+v", cgroups[0])
+	}
+	if cgroups[2].HierarchyID != 0 || cgroups[2].Controllers != nil || cgroups[2].Path != "/system.slice/sshd.service" {
+		t.Errorf("cgroup v2 = %+v", cgroups[2])
+	}
+}
+
+func TestContainerID(t *testing.T) {
+	const id = "3f9a2b1c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a"
+
+	tests := []struct {
+		path    string
+		runtime string
+	}{
+		{"/docker/" + id, RuntimeDocker},
+		{"/system.slice/docker-" + id + ".scope", RuntimeDocker},
+		{"/system.slice/containerd.service/k8s.io/" + id, RuntimeContainerd},
+		{"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1234.slice/cri-containerd-" + id + ".scope", RuntimeContainerd},
+		{"/kubepods.slice/kubepods-pod1234.slice/crio-" + id + ".scope", RuntimeCRIO},
+		{"/kubepods/besteffort/pod1234/" + id, RuntimeKubepods},
+		{"/machine.slice/libpod-" + id + ".scope", RuntimePodman},
+		{"/user.slice/user-1000.slice/session-2.scope", ""},
+		{"/", ""},
+	}
+
+	for _, tt := range tests {
+		gotID, runtime := ContainerID(tt.path)
+		if runtime != tt.runtime {
+			t.Errorf("%s: runtime = %q, want %q", tt.path, runtime, tt.runtime)
+		}
+		if tt.runtime != "" && gotID != id {
+			t.Errorf("%s: id = %q, want %q", tt.path, gotID, id)
+		}
+	}
+}
+
+func TestParseCgroupMounts(t *testing.T) {
+	lines := []string{
+		"32 24 0:28 / /sys/fs/cgroup rw,relatime - tmpfs tmpfs rw,mode=755",
+		"33 32 0:29 / /sys/fs/cgroup/cpu,cpuacct rw,nosuid shared:9 - cgroup cgroup rw,cpu,cpuacct",
+		"41 32 0:37 / /sys/fs/cgroup/systemd rw,relatime - cgroup cgroup rw,xattr,release_agent=/x,name=systemd",
+		"42 32 0:38 /docker/0123 /sys/fs/cgroup/unified rw,relatime - cgroup2 cgroup2 rw,nsdelegate",
+	}
+
+	mounts := ParseCgroupMounts(lines)
+	if len(mounts) != 3 {
+		t.Fatalf("got %d mounts, want 3", len(mounts))
+	}
+
+	if m := mounts[0]; m.Version != 1 || !m.HasController("cpuacct") || m.Mountpoint != "/sys/fs/cgroup/cpu,cpuacct" {
+		t.Errorf("mount = %+v", m)
+	}
+	if m := mounts[1]; strings.Join(m.Controllers, ",") != "name=systemd" {
+		t.Errorf("mount = %+v", m)
+	}
+
+	m := mounts[2]
+	if m.Version != 2 || m.Controllers != nil {
+		t.Errorf("mount = %+v", m)
+	}
+	if p := m.Path("/docker/0123/app"); p != "/sys/fs/cgroup/unified/app" {
+		t.Errorf("got path %q", p)
+	}
+	if p := m.Path("/system.slice"); p != "" {
+		t.Errorf("got path %q", p)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const _AT_CLKTCK = 17
+
+var (
+	clockTicksOnce sync.Once
+	clockTicks     uint64 = 100
+)
+
+// ClockTicks returns the number of clock ticks per second (USER_HZ) used by
+// the times in /proc. The value comes from the auxiliary vector since
+// sysconf(_SC_CLK_TCK) needs cgo.
+func ClockTicks() uint64 {
+	clockTicksOnce.Do(func() {
+		b, err := ioutil.ReadFile(ProcPath("self", "auxv"))
+		if err != nil {
+			return
+		}
+
+		const size = unsafe.Sizeof(uintptr(0))
+		for i := 0; i+int(2*size) <= len(b); i += int(2 * size) {
+			tag := *(*uintptr)(unsafe.Pointer(&b[i]))
+			val := *(*uintptr)(unsafe.Pointer(&b[i+int(size)]))
+			if tag == _AT_CLKTCK && val != 0 {
+				clockTicks = uint64(val)
+				return
+			}
+		}
+	})
+	return clockTicks
+}
+
+// TicksToDuration converts clock ticks to a duration.
+func TicksToDuration(ticks uint64) time.Duration {
+	return time.Duration(ticks) * (time.Second / time.Duration(ClockTicks()))
+}
+
+var (
+	bootTimeOnce sync.Once
+	bootTime     time.Time
+	bootTimeErr  error
+)
+
+// BootTime returns the time the system booted, from the btime line of
+// /proc/stat.
+func BootTime() (time.Time, error) {
+	bootTimeOnce.Do(func() {
+		lines, err := ReadLines(ProcPath("stat"))
+		if err != nil {
+			bootTimeErr = err
+			return
+		}
+
+		for _, l := range lines {
+			f := strings.Fields(l)
+			if len(f) == 2 && f[0] == "btime" {
+				secs, err := strconv.ParseInt(f[1], 10, 64)
+				if err != nil {
+					bootTimeErr = err
+					return
+				}
+				bootTime = time.Unix(secs, 0)
+				return
+			}
+		}
+		bootTimeErr = errors.New("btime not found in /proc/stat")
+	})
+	return bootTime, bootTimeErr
+}
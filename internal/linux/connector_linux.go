@@ -0,0 +1,140 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"encoding/binary"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Netlink process connector, see linux/cn_proc.h.
+const (
+	NETLINK_CONNECTOR = 11
+
+	CN_IDX_PROC = 1
+	CN_VAL_PROC = 1
+
+	PROC_CN_MCAST_LISTEN = 1
+	PROC_CN_MCAST_IGNORE = 2
+
+	PROC_EVENT_NONE = 0x00000000
+	PROC_EVENT_FORK = 0x00000001
+	PROC_EVENT_EXEC = 0x00000002
+	PROC_EVENT_EXIT = 0x80000000
+)
+
+const (
+	sizeofNlMsghdr = 16
+	sizeofCnMsg    = 20
+	// what, cpu, timestamp_ns
+	sizeofProcEventHeader = 16
+)
+
+// NativeEndian is the byte order of the running architecture, used by
+// netlink messages.
+var NativeEndian binary.ByteOrder = nativeEndian()
+
+func nativeEndian() binary.ByteOrder {
+	var x uint16 = 1
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// ProcEvent is a fork, exec or exit event of the process connector. Pid is
+// the thread id and Tgid the process id of the task the event is about.
+type ProcEvent struct {
+	What       uint32
+	Pid        uint32
+	Tgid       uint32
+	ParentPid  uint32 // fork only
+	ParentTgid uint32 // fork only
+	ExitCode   uint32 // exit only
+}
+
+// OpenProcConnector subscribes to the process connector. Joining the
+// multicast group requires CAP_NET_ADMIN.
+func OpenProcConnector() (int, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, NETLINK_CONNECTOR)
+	if err != nil {
+		return -1, os.NewSyscallError("socket", err)
+	}
+
+	sa := &syscall.SockaddrNetlink{
+		Family : syscall.AF_NETLINK,
+		Groups : CN_IDX_PROC,
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return -1, os.NewSyscallError("bind", err)
+	}
+
+	// nlmsghdr, cn_msg, enum proc_cn_mcast_op
+	msg := make([]byte, sizeofNlMsghdr + sizeofCnMsg + 4)
+	NativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	NativeEndian.PutUint16(msg[4:], syscall.NLMSG_DONE)
+	NativeEndian.PutUint32(msg[12:], uint32(os.Getpid()))
+	NativeEndian.PutUint32(msg[16:], CN_IDX_PROC)
+	NativeEndian.PutUint32(msg[20:], CN_VAL_PROC)
+	NativeEndian.PutUint16(msg[32:], 4)
+	NativeEndian.PutUint32(msg[36:], PROC_CN_MCAST_LISTEN)
+
+	if err := syscall.Sendto(fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return -1, os.NewSyscallError("sendto", err)
+	}
+	return fd, nil
+}
+
+// ReadProcEvents reads the next datagram from the connector. It fails with
+// ENOBUFS when events were dropped because they were not read fast enough.
+func ReadProcEvents(fd int, buf []byte) ([]ProcEvent, error) {
+	n, _, err := syscall.Recvfrom(fd, buf, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []ProcEvent
+	for _, m := range msgs {
+		d := m.Data
+		if len(d) < sizeofCnMsg + sizeofProcEventHeader {
+			continue
+		}
+		if NativeEndian.Uint32(d[0:]) != CN_IDX_PROC || NativeEndian.Uint32(d[4:]) != CN_VAL_PROC {
+			continue
+		}
+
+		ev := d[sizeofCnMsg:]
+		data := ev[sizeofProcEventHeader:]
+		u := func(off int) uint32 {
+			if off+4 > len(data) {
+				return 0
+			}
+			return NativeEndian.Uint32(data[off:])
+		}
+
+		pe := ProcEvent{What: NativeEndian.Uint32(ev[0:])}
+		switch pe.What {
+		case PROC_EVENT_FORK:
+			pe.ParentPid, pe.ParentTgid, pe.Pid, pe.Tgid = u(0), u(4), u(8), u(12)
+		case PROC_EVENT_EXEC:
+			pe.Pid, pe.Tgid = u(0), u(4)
+		case PROC_EVENT_EXIT:
+			pe.Pid, pe.Tgid, pe.ExitCode = u(0), u(4), u(8)
+		default:
+			continue
+		}
+		ret = append(ret, pe)
+	}
+	return ret, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseCPUList parses the list format of sysfs and cgroups, "0-3,8,10-11",
+// into CPU or node numbers in ascending order. An empty list is valid.
+func ParseCPUList(s string) ([]int, error) {
+	var ret []int
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ret, nil
+	}
+
+	for _, r := range strings.Split(s, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+
+		lo, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed cpu list: %q", s)
+		}
+		hi := lo
+		if len(bounds) == 2 {
+			if hi, err = strconv.Atoi(bounds[1]); err != nil || hi < lo {
+				return nil, fmt.Errorf("malformed cpu list: %q", s)
+			}
+		}
+
+		for n := lo; n <= hi; n++ {
+			ret = append(ret, n)
+		}
+	}
+	return ret, nil
+}
+
+// ReadCPUList reads a file holding a cpu list.
+func ReadCPUList(path string) ([]int, error) {
+	s, err := ReadString(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCPUList(s)
+}
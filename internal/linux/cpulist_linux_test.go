@@ -0,0 +1,29 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := ParseCPUList("0-3,8,10-11\n")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if fmt.Sprint(cpus) != "[0 1 2 3 8 10 11]" {
+		t.Errorf("got %v", cpus)
+	}
+
+	if cpus, err := ParseCPUList(""); err != nil || len(cpus) != 0 {
+		t.Errorf("got %v, %v", cpus, err)
+	}
+	for _, s := range []string{"a", "3-1", "1,"} {
+		if _, err := ParseCPUList(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package linuxtest provides a fake proc and sys tree for the tests of the
+// Linux backends.
+package linuxtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// FakeTree creates files under a temporary directory and points the proc
+// and sys roots at its proc and sys subdirectories. "$ROOT" in the content
+// of the files is replaced by the directory, for mount points. The returned
+// function restores the roots and removes the directory.
+func FakeTree(t *testing.T, files map[string]string) func() {
+	dir, err := ioutil.TempDir("", "sysmon")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content = strings.Replace(content, "$ROOT", dir, -1)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	procRoot, sysRoot := linux.ProcRoot, linux.SysRoot
+	linux.ProcRoot = filepath.Join(dir, "proc")
+	linux.SysRoot = filepath.Join(dir, "sys")
+
+	return func() {
+		linux.ProcRoot, linux.SysRoot = procRoot, sysRoot
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// pidfd system calls share the same number on every architecture.
+const (
+	SYS_PIDFD_SEND_SIGNAL = 424
+	SYS_PIDFD_OPEN        = 434
+)
+
+const (
+	P_PIDFD = 3 // idtype for waitid(2)
+
+	CLD_EXITED = 1
+	CLD_KILLED = 2
+	CLD_DUMPED = 3
+
+	POLLIN  = 0x1
+	POLLPRI = 0x2
+	POLLERR = 0x8
+)
+
+// The union of siginfo_t holds pointers, so it starts at offset 16 on 64-bit
+// architectures and right after the three int header fields on 32-bit ones.
+const siginfoPad = unsafe.Sizeof(uintptr(0)) - 4
+
+// Siginfo is the layout of siginfo_t filled by waitid(2) for SIGCHLD.
+type Siginfo struct {
+	Signo  int32
+	Errno  int32
+	Code   int32
+	_      [siginfoPad]byte
+	Pid    int32
+	Uid    uint32
+	Status int32
+	_      [128 - 24 - siginfoPad]byte
+}
+
+type pollFd struct {
+	Fd      int32
+	Events  int16
+	Revents int16
+}
+
+// PidfdOpen returns a file descriptor referring to the process pid. Unlike
+// the pid itself, the descriptor keeps referring to the same process after
+// it exits, so it can never be used to signal a recycled pid.
+func PidfdOpen(pid uint32) (int, error) {
+	r, _, e := syscall.Syscall(SYS_PIDFD_OPEN, uintptr(pid), 0, 0)
+	if e != 0 {
+		return -1, e
+	}
+	syscall.CloseOnExec(int(r))
+	return int(r), nil
+}
+
+// PidfdSendSignal sends sig to the process referred to by fd.
+func PidfdSendSignal(fd int, sig syscall.Signal) error {
+	_, _, e := syscall.Syscall6(SYS_PIDFD_SEND_SIGNAL, uintptr(fd), uintptr(sig), 0, 0, 0, 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// WaitidPidfd calls waitid(2) on the process referred to by fd. It fails
+// with ECHILD when the process is not a child of the caller.
+func WaitidPidfd(fd int, options int) (*Siginfo, error) {
+	var info Siginfo
+
+	_, _, e := syscall.Syscall6(syscall.SYS_WAITID, P_PIDFD, uintptr(fd), uintptr(unsafe.Pointer(&info)), uintptr(options), 0, 0)
+	if e != 0 {
+		return nil, e
+	}
+	return &info, nil
+}
+
+// Poll waits up to timeout for one of events on fd and returns the events
+// that occurred. A negative timeout blocks indefinitely.
+func Poll(fd int, events int16, timeout time.Duration) (int16, error) {
+	pfd := pollFd{Fd: int32(fd), Events: events}
+
+	var ts *syscall.Timespec
+	if timeout >= 0 {
+		t := syscall.NsecToTimespec(int64(timeout))
+		ts = &t
+	}
+
+	n, _, e := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(&pfd)), 1, uintptr(unsafe.Pointer(ts)), 0, 0, 0)
+	if e != 0 {
+		return 0, e
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return pfd.Revents, nil
+}
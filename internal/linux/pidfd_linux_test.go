@@ -0,0 +1,58 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func TestSiginfoLayout(t *testing.T) {
+	var info Siginfo
+
+	want := uintptr(16)
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		want = 12
+	}
+	if off := unsafe.Offsetof(info.Pid); off != want {
+		t.Errorf("Pid at offset %d, want %d", off, want)
+	}
+	if size := unsafe.Sizeof(info); size != 128 {
+		t.Errorf("size = %d, want 128", size)
+	}
+}
+
+// Run with GOARCH=386 as well, the siginfo_t layout differs on 32-bit.
+func TestWaitidPidfd(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 3")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	pid := cmd.Process.Pid
+
+	fd, err := PidfdOpen(uint32(pid))
+	if err == syscall.ENOSYS {
+		cmd.Wait()
+		t.Skip("no pidfd support")
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer syscall.Close(fd)
+
+	if _, err := Poll(fd, POLLIN, -1); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	info, err := WaitidPidfd(fd, syscall.WEXITED)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if info.Pid != int32(pid) || info.Code != CLD_EXITED || info.Status != 3 {
+		t.Errorf("got pid %d, code %d, status %d, want %d, %d, 3", info.Pid, info.Code, info.Status, pid, CLD_EXITED)
+	}
+}
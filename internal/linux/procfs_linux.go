@@ -0,0 +1,158 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mount points of the proc and sys pseudo file systems. Tests point them
+// at a fake tree.
+var (
+	ProcRoot = "/proc"
+	SysRoot  = "/sys"
+)
+
+// ProcPath joins elem to the proc mount point.
+func ProcPath(elem ...string) string {
+	return filepath.Join(append([]string{ProcRoot}, elem...)...)
+}
+
+// SysPath joins elem to the sys mount point.
+func SysPath(elem ...string) string {
+	return filepath.Join(append([]string{SysRoot}, elem...)...)
+}
+
+// PidPath returns the path of a file in /proc/<pid>.
+func PidPath(pid uint32, elem ...string) string {
+	return ProcPath(append([]string{strconv.FormatUint(uint64(pid), 10)}, elem...)...)
+}
+
+// TidPath returns the path of a file in /proc/<pid>/task/<tid>.
+func TidPath(pid, tid uint32, elem ...string) string {
+	return PidPath(pid, append([]string{"task", strconv.FormatUint(uint64(tid), 10)}, elem...)...)
+}
+
+// ReadString returns the content of a file with surrounding white space removed.
+func ReadString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// ReadUint reads a file holding a single unsigned integer.
+func ReadUint(path string) (uint64, error) {
+	s, err := ReadString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// ReadLines returns the lines of a file.
+func ReadLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// ReadKeyValues parses files made of "Key: value" lines such as
+// /proc/<pid>/status or /proc/meminfo.
+func ReadKeyValues(path string) (map[string]string, error) {
+	lines, err := ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := make(map[string]string, len(lines))
+	for _, l := range lines {
+		i := strings.IndexByte(l, ':')
+		if i < 0 {
+			continue
+		}
+		kv[strings.TrimSpace(l[:i])] = strings.TrimSpace(l[i+1:])
+	}
+	return kv, nil
+}
+
+// ParseKB converts values such as "1024 kB" to bytes.
+func ParseKB(s string) uint64 {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return 0
+	}
+	n, _ := strconv.ParseUint(f[0], 10, 64)
+	if len(f) > 1 && strings.EqualFold(f[1], "kB") {
+		n *= 1024
+	}
+	return n
+}
+
+// Pids lists the process ids found in /proc in ascending order.
+func Pids() ([]uint32, error) {
+	return numericEntries(ProcRoot)
+}
+
+// Tids lists the thread ids of a process.
+func Tids(pid uint32) ([]uint32, error) {
+	return numericEntries(PidPath(pid, "task"))
+}
+
+func numericEntries(dir string) ([]uint32, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []uint32
+	for _, name := range names {
+		n, err := strconv.ParseUint(name, 10, 32)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, uint32(n))
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+
+	return ret, nil
+}
+
+// CountEntries returns the number of entries in a directory, for example
+// /proc/<pid>/fd.
+func CountEntries(dir string) (int, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer d.Close()
+
+	names, err := d.Readdirnames(-1)
+	return len(names), err
+}
@@ -0,0 +1,108 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package linux
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Stat holds the fields of /proc/<pid>/stat and /proc/<pid>/task/<tid>/stat
+// used by this module. Times are in clock ticks, see ClockTicks.
+type Stat struct {
+	Pid         uint32
+	Comm        string
+	State       byte
+	PPid        uint32
+	Pgrp        int32
+	Session     int32
+	TtyNr       int32
+	MinFlt      uint64
+	MajFlt      uint64
+	UTime       uint64
+	STime       uint64
+	Priority    int64
+	Nice        int64
+	NumThreads  int64
+	StartTime   uint64 // time the process started after system boot, in clock ticks
+	VSize       uint64 // virtual memory size in bytes
+	Rss         int64  // resident set size in pages
+	Processor   int64  // CPU number last executed on
+	RtPriority  uint64
+	Policy      uint64
+	BlkioTicks  uint64 // aggregated block I/O delays
+}
+
+// ReadStat parses a stat file.
+func ReadStat(path string) (*Stat, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStat(string(b))
+}
+
+// ParseStat parses the content of a stat file. The command name is enclosed
+// in parentheses and may itself contain spaces and parentheses, so the
+// remaining fields are located from the last closing parenthesis.
+func ParseStat(s string) (*Stat, error) {
+	open := strings.IndexByte(s, '(')
+	end := strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("malformed stat: %q", s)
+	}
+
+	pid, err := strconv.ParseUint(strings.TrimSpace(s[:open]), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("malformed stat pid: %v", err)
+	}
+
+	// f[0] is field 3 (state) in proc(5).
+	f := strings.Fields(s[end+1:])
+	if len(f) < 20 {
+		return nil, fmt.Errorf("malformed stat: %d fields", len(f))
+	}
+
+	field := func(n int) string {
+		if n-3 < len(f) {
+			return f[n-3]
+		}
+		return "0"
+	}
+	u := func(n int) uint64 {
+		v, _ := strconv.ParseUint(field(n), 10, 64)
+		return v
+	}
+	i := func(n int) int64 {
+		v, _ := strconv.ParseInt(field(n), 10, 64)
+		return v
+	}
+
+	return &Stat{
+		Pid        : uint32(pid),
+		Comm       : s[open+1 : end],
+		State      : field(3)[0],
+		PPid       : uint32(u(4)),
+		Pgrp       : int32(i(5)),
+		Session    : int32(i(6)),
+		TtyNr      : int32(i(7)),
+		MinFlt     : u(10),
+		MajFlt     : u(12),
+		UTime      : u(14),
+		STime      : u(15),
+		Priority   : i(18),
+		Nice       : i(19),
+		NumThreads : i(20),
+		StartTime  : u(22),
+		VSize      : u(23),
+		Rss        : i(24),
+		Processor  : i(39),
+		RtPriority : u(40),
+		Policy     : u(41),
+		BlkioTicks : u(42),
+	}, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package win32
+
+import (
+	"fmt"
+	"syscall"
+)
+
+var (
+	modntdll = syscall.NewLazyDLL("ntdll.dll")
+
+	procNtSuspendProcess = modntdll.NewProc("NtSuspendProcess")
+	procNtResumeProcess  = modntdll.NewProc("NtResumeProcess")
+)
+
+const (
+	PROCESS_SUSPEND_RESUME = 0x0800
+)
+
+// NTStatus is the status code returned by the native API.
+type NTStatus uint32
+
+func (s NTStatus) Error() string {
+	return fmt.Sprintf("NTSTATUS 0x%08X", uint32(s))
+}
+
+// Suspends all threads of the process. The handle needs PROCESS_SUSPEND_RESUME.
+func NtSuspendProcess(h syscall.Handle) error {
+	r, _, _ := procNtSuspendProcess.Call(uintptr(h))
+
+	if r != 0 {
+		return NTStatus(r)
+	}
+
+	return nil
+}
+
+// Resumes a process suspended by NtSuspendProcess. The handle needs PROCESS_SUSPEND_RESUME.
+func NtResumeProcess(h syscall.Handle) error {
+	r, _, _ := procNtResumeProcess.Call(uintptr(h))
+
+	if r != 0 {
+		return NTStatus(r)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package win32
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modpowrprof = syscall.NewLazyDLL("powrprof.dll")
+
+	procCallNtPowerInformation = modpowrprof.NewProc("CallNtPowerInformation")
+)
+
+// POWER_INFORMATION_LEVEL
+const (
+	ProcessorInformation = 11
+)
+
+const (
+	STATUS_BUFFER_TOO_SMALL = 0xC0000023
+)
+
+// PROCESSOR_POWER_INFORMATION, one per logical processor.
+type ProcessorPowerInformation struct {
+	Number           uint32
+	MaxMhz           uint32 // The maximum frequency.
+	CurrentMhz       uint32 // The current frequency.
+	MhzLimit         uint32 // The limit set by power management, such as thermal throttling.
+	MaxIdleState     uint32
+	CurrentIdleState uint32
+}
+
+// Returns the power information of the logical processors.
+func CallNtPowerInformationProcessors(count int) ([]ProcessorPowerInformation, error) {
+	for {
+		buf := make([]ProcessorPowerInformation, count)
+		size := uintptr(count) * unsafe.Sizeof(buf[0])
+
+		r, _, _ := procCallNtPowerInformation.Call(
+			ProcessorInformation,
+			0,
+			0,
+			uintptr(unsafe.Pointer(&buf[0])),
+			size)
+
+		if r == STATUS_BUFFER_TOO_SMALL {
+			count *= 2
+			continue
+		}
+		if r != 0 {
+			return nil, NTStatus(r)
+		}
+
+		return buf, nil
+	}
+}
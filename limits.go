@@ -0,0 +1,94 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sysmon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResourceLimits holds the limits that apply to the current process, such
+// as those of the container it runs in, along with the usage they are
+// checked against.
+type ResourceLimits struct {
+	Cgroup  string      `json:"cgroup"`  // cgroup of the process, in the unified hierarchy or the memory one
+	Version int         `json:"version"` // cgroup version, 0 when the process is not in a cgroup
+	CPU     CPULimit    `json:"cpu"`
+	Memory  MemoryLimit `json:"memory"`
+	Pids    PidsLimit   `json:"pids"`
+}
+
+func (rl ResourceLimits) GoString() string {
+	s := []string{"ResourceLimits{",
+			fmt.Sprintf("  Cgroup  : %s", rl.Cgroup),
+			fmt.Sprintf("  Version : %d", rl.Version),
+			fmt.Sprintf("  Cores   : %.2f", rl.CPU.Cores),
+			fmt.Sprintf("  CPUSet  : %v", rl.CPU.CPUSet),
+			fmt.Sprintf("  Memory  : %s of %s", rl.Memory.Usage, rl.Memory.Max),
+			fmt.Sprintf("  Pids    : %d of %d", rl.Pids.Current, rl.Pids.Max),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// CPULimit is the CPU bandwidth granted to the cgroup: Quota of CPU time
+// every Period, spread over the CPUs of CPUSet.
+type CPULimit struct {
+	Quota         time.Duration `json:"quota"`         // 0 when unlimited
+	Period        time.Duration `json:"period"`
+	CPUSet        []int         `json:"cpuSet"`        // CPUs the process may run on
+	Cores         float64       `json:"cores"`         // CPUs worth of time available, the lower of Quota/Period and len(CPUSet)
+	Usage         time.Duration `json:"usage"`         // CPU time used by the cgroup
+	Periods       uint64        `json:"periods"`       // elapsed enforcement periods
+	Throttled     uint64        `json:"throttled"`     // periods in which the quota ran out
+	ThrottledTime time.Duration `json:"throttledTime"` // time spent throttled
+}
+
+// UsedPercent returns the share of Cores used between prev, a reading taken
+// elapsed earlier, and this one.
+func (cl CPULimit) UsedPercent(prev CPULimit, elapsed time.Duration) float64 {
+	if elapsed <= 0 || cl.Cores == 0 {
+		return 0
+	}
+	return float64(cl.Usage - prev.Usage) / (float64(elapsed) * cl.Cores) * 100
+}
+
+// MemoryLimit holds the memory limits of the cgroup. Usage includes the page
+// cache, which the kernel reclaims before hitting Max.
+type MemoryLimit struct {
+	Max   Size `json:"max"`   // hard limit, 0 when unlimited
+	High  Size `json:"high"`  // throttling threshold, 0 when unlimited or on cgroup v1
+	Usage Size `json:"usage"`
+}
+
+// UsedPercent returns Usage as a percentage of Max, 0 when unlimited.
+func (ml MemoryLimit) UsedPercent() float64 {
+	if ml.Max == 0 {
+		return 0
+	}
+	return float64(ml.Usage) / float64(ml.Max) * 100
+}
+
+type PidsLimit struct {
+	Max     uint64 `json:"max"`     // most tasks allowed, 0 when unlimited
+	Current uint64 `json:"current"` // tasks in the cgroup, threads included
+}
+
+// UsedPercent returns Current as a percentage of Max, 0 when unlimited.
+func (pl PidsLimit) UsedPercent() float64 {
+	if pl.Max == 0 {
+		return 0
+	}
+	return float64(pl.Current) / float64(pl.Max) * 100
+}
+
+// Limits returns the effective resource limits of the current process. A
+// limit set on a parent cgroup applies when it is lower than the one of the
+// process cgroup. Use it instead of the host CPU count and memory size to
+// size thread pools and caches inside containers.
+func Limits() (*ResourceLimits, error) {
+	return limits()
+}
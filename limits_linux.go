@@ -0,0 +1,262 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sysmon
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// cgroupDir is where a controller of the process cgroup is mounted.
+type cgroupDir struct {
+	path       string // cgroup path, as in /proc/self/cgroup
+	dir        string
+	mountpoint string
+	version    int
+}
+
+// walk calls fn with the cgroup directory and those of its parents visible
+// under the mount point.
+func (cg cgroupDir) walk(fn func(dir string)) {
+	for dir := cg.dir; ; dir = filepath.Dir(dir) {
+		fn(dir)
+		if len(dir) <= len(cg.mountpoint) {
+			return
+		}
+	}
+}
+
+// lowest returns the lowest limit set in file by the cgroup or its parents,
+// 0 when none is.
+func (cg cgroupDir) lowest(file string) uint64 {
+	var min uint64
+	cg.walk(func(dir string) {
+		if v := readLimit(dir + "/" + file); v != 0 && (min == 0 || v < min) {
+			min = v
+		}
+	})
+	return min
+}
+
+// findCgroup returns the cgroup of the process for controller. A controller
+// bound to a cgroup v1 hierarchy is not available in the unified one.
+func findCgroup(entries []linux.CgroupEntry, mounts []linux.CgroupMount, controller string) (cgroupDir, bool) {
+	for _, e := range entries {
+		if e.HierarchyID == 0 || !hasController(e, controller) {
+			continue
+		}
+		for _, m := range mounts {
+			if m.Version != 1 || !m.HasController(controller) {
+				continue
+			}
+			if dir := m.Path(e.Path); dir != "" {
+				return cgroupDir{e.Path, dir, m.Mountpoint, 1}, true
+			}
+		}
+	}
+
+	for _, e := range entries {
+		if e.HierarchyID != 0 {
+			continue
+		}
+		for _, m := range mounts {
+			if m.Version != 2 {
+				continue
+			}
+			if dir := m.Path(e.Path); dir != "" {
+				return cgroupDir{e.Path, dir, m.Mountpoint, 2}, true
+			}
+		}
+	}
+	return cgroupDir{}, false
+}
+
+func hasController(e linux.CgroupEntry, controller string) bool {
+	for _, c := range e.Controllers {
+		if c == controller {
+			return true
+		}
+	}
+	return false
+}
+
+func limits() (*ResourceLimits, error) {
+	entries, err := linux.ReadCgroups(linux.ProcPath("self", "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+
+	mounts, err := linux.ReadCgroupMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	rl := &ResourceLimits{}
+	find := func(controller string) (cgroupDir, bool) {
+		cg, ok := findCgroup(entries, mounts, controller)
+		if ok && rl.Version == 0 {
+			rl.Cgroup, rl.Version = cg.path, cg.version
+		}
+		return cg, ok
+	}
+
+	if cg, ok := find("memory"); ok {
+		rl.Memory = memoryLimit(cg)
+	}
+
+	rl.CPU.CPUSet = cpuSet(find("cpuset"))
+	if cg, ok := find("cpu"); ok {
+		cpuQuota(cg, &rl.CPU)
+	}
+	if cg, ok := find("cpuacct"); ok && cg.version == 1 {
+		if ns, err := linux.ReadUint(cg.dir + "/cpuacct.usage"); err == nil {
+			rl.CPU.Usage = time.Duration(ns)
+		}
+	}
+
+	rl.CPU.Cores = float64(len(rl.CPU.CPUSet))
+	if rl.CPU.Cores == 0 {
+		rl.CPU.Cores = float64(runtime.NumCPU())
+	}
+	if rl.CPU.Quota > 0 && rl.CPU.Period > 0 {
+		if q := float64(rl.CPU.Quota) / float64(rl.CPU.Period); q < rl.CPU.Cores {
+			rl.CPU.Cores = q
+		}
+	}
+
+	if cg, ok := find("pids"); ok {
+		rl.Pids.Max = cg.lowest("pids.max")
+		rl.Pids.Current, _ = linux.ReadUint(cg.dir + "/pids.current")
+	}
+	return rl, nil
+}
+
+func memoryLimit(cg cgroupDir) MemoryLimit {
+	var ml MemoryLimit
+
+	if cg.version == 1 {
+		ml.Max = Size(cg.lowest("memory.limit_in_bytes"))
+		usage, _ := linux.ReadUint(cg.dir + "/memory.usage_in_bytes")
+		ml.Usage = Size(usage)
+		return ml
+	}
+
+	ml.Max = Size(cg.lowest("memory.max"))
+	ml.High = Size(cg.lowest("memory.high"))
+	usage, _ := linux.ReadUint(cg.dir + "/memory.current")
+	ml.Usage = Size(usage)
+	return ml
+}
+
+// cpuQuota reads the CPU bandwidth limit, keeping the one of the cgroup or
+// its parents that grants the fewest CPUs, and the throttling counters.
+func cpuQuota(cg cgroupDir, cl *CPULimit) {
+	var cores float64
+
+	cg.walk(func(dir string) {
+		var quota, period uint64
+		if cg.version == 1 {
+			quota = readLimit(dir + "/cpu.cfs_quota_us")
+			period, _ = linux.ReadUint(dir + "/cpu.cfs_period_us")
+		} else {
+			// "$MAX $PERIOD", $MAX being "max" when unlimited.
+			s, err := linux.ReadString(dir + "/cpu.max")
+			if err != nil {
+				return
+			}
+			f := strings.Fields(s)
+			if len(f) != 2 {
+				return
+			}
+			quota = parseLimit(f[0])
+			period, _ = strconv.ParseUint(f[1], 10, 64)
+		}
+
+		if quota == 0 || period == 0 {
+			return
+		}
+		if c := float64(quota) / float64(period); cores == 0 || c < cores {
+			cores = c
+			cl.Quota = time.Duration(quota) * time.Microsecond
+			cl.Period = time.Duration(period) * time.Microsecond
+		}
+	})
+
+	stat := readFlatKeyed(cg.dir + "/cpu.stat")
+	cl.Periods = stat["nr_periods"]
+	cl.Throttled = stat["nr_throttled"]
+	if cg.version == 1 {
+		cl.ThrottledTime = time.Duration(stat["throttled_time"])
+	} else {
+		cl.ThrottledTime = time.Duration(stat["throttled_usec"]) * time.Microsecond
+		cl.Usage = time.Duration(stat["usage_usec"]) * time.Microsecond
+	}
+}
+
+// cpuSet returns the CPUs of the cgroup cpuset, or the CPUs the process may
+// run on when the cpuset controller is not enabled.
+func cpuSet(cg cgroupDir, ok bool) []int {
+	if ok {
+		file := "cpuset.cpus.effective"
+		if cg.version == 1 {
+			file = "cpuset.effective_cpus"
+		}
+		if cpus, err := linux.ReadCPUList(cg.dir + "/" + file); err == nil && len(cpus) > 0 {
+			return cpus
+		}
+	}
+
+	status, err := linux.ReadKeyValues(linux.ProcPath("self", "status"))
+	if err != nil {
+		return nil
+	}
+	cpus, _ := linux.ParseCPUList(status["Cpus_allowed_list"])
+	return cpus
+}
+
+// readLimit reads a cgroup file holding a limit, see parseLimit. Missing
+// files read as unlimited.
+func readLimit(path string) uint64 {
+	s, err := linux.ReadString(path)
+	if err != nil {
+		return 0
+	}
+	return parseLimit(s)
+}
+
+// parseLimit returns 0 for "max", negative values and the huge values that
+// cgroup v1 reports for unlimited (PAGE_COUNTER_MAX pages).
+func parseLimit(s string) uint64 {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n >= 1<<62 {
+		return 0
+	}
+	return n
+}
+
+// readFlatKeyed parses cgroup files made of "key value" lines.
+func readFlatKeyed(path string) map[string]uint64 {
+	lines, err := linux.ReadLines(path)
+	if err != nil {
+		return nil
+	}
+
+	kv := make(map[string]uint64, len(lines))
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseUint(f[1], 10, 64); err == nil {
+			kv[f[0]] = n
+		}
+	}
+	return kv
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sysmon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux/linuxtest"
+)
+
+func TestLimitsV2(t *testing.T) {
+	const pod = "cgroup/kubepods.slice/kubepods-burstable.slice/pod1"
+
+	defer linuxtest.FakeTree(t, map[string]string{
+		"proc/self/cgroup":     "0::/kubepods.slice/kubepods-burstable.slice/pod1/cri-containerd-1.scope\n",
+		"proc/self/mountinfo":  "30 23 0:26 / $ROOT/cgroup rw,nosuid - cgroup2 cgroup2 rw,nsdelegate\n",
+		"proc/self/status":     "Cpus_allowed_list:\t0-15\n",
+		pod + "/cpu.max":       "400000 100000\n",
+		pod + "/memory.max":    "4294967296\n",
+		pod + "/pids.max":      "max\n",
+		pod + "/cri-containerd-1.scope/cpu.max":               "250000 100000\n",
+		pod + "/cri-containerd-1.scope/cpu.stat":              "usage_usec 5000000\nnr_periods 50\nnr_throttled 5\nthrottled_usec 120000\n",
+		pod + "/cri-containerd-1.scope/cpuset.cpus.effective": "0-7\n",
+		pod + "/cri-containerd-1.scope/memory.max":            "max\n",
+		pod + "/cri-containerd-1.scope/memory.high":           "3221225472\n",
+		pod + "/cri-containerd-1.scope/memory.current":        "1073741824\n",
+		pod + "/cri-containerd-1.scope/pids.max":              "1024\n",
+		pod + "/cri-containerd-1.scope/pids.current":          "256\n",
+	})()
+
+	rl, err := Limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rl.Version != 2 || !strings.HasSuffix(rl.Cgroup, "/cri-containerd-1.scope") {
+		t.Errorf("cgroup = %s v%d", rl.Cgroup, rl.Version)
+	}
+
+	cl := rl.CPU
+	if cl.Quota != 250 * time.Millisecond || cl.Period != 100 * time.Millisecond || cl.Cores != 2.5 {
+		t.Errorf("quota = %v/%v, cores = %v", cl.Quota, cl.Period, cl.Cores)
+	}
+	if len(cl.CPUSet) != 8 {
+		t.Errorf("cpuset = %v", cl.CPUSet)
+	}
+	if cl.Usage != 5 * time.Second || cl.Periods != 50 || cl.Throttled != 5 || cl.ThrottledTime != 120 * time.Millisecond {
+		t.Errorf("cpu stat = %+v", cl)
+	}
+
+	// The pod limit applies to the container.
+	if rl.Memory.Max != 4 * GB || rl.Memory.High != 3 * GB || rl.Memory.Usage != GB {
+		t.Errorf("memory = %+v", rl.Memory)
+	}
+	if p := rl.Memory.UsedPercent(); p != 25 {
+		t.Errorf("memory used = %v%%", p)
+	}
+
+	if rl.Pids.Max != 1024 || rl.Pids.Current != 256 || rl.Pids.UsedPercent() != 25 {
+		t.Errorf("pids = %+v", rl.Pids)
+	}
+
+	prev := cl
+	cl.Usage += 1250 * time.Millisecond
+	if p := cl.UsedPercent(prev, time.Second); p != 50 {
+		t.Errorf("cpu used = %v%%", p)
+	}
+}
+
+func TestLimitsV1(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{
+		"proc/self/cgroup": strings.Join([]string{
+			"5:pids:/docker/abc",
+			"4:memory:/docker/abc",
+			"3:cpuset:/docker/abc",
+			"2:cpu,cpuacct:/docker/abc",
+			"0::/system.slice/containerd.service",
+		}, "\n"),
+		"proc/self/mountinfo": strings.Join([]string{
+			"33 32 0:29 /docker/abc $ROOT/cgroup/cpu,cpuacct rw - cgroup cgroup rw,cpu,cpuacct",
+			"35 32 0:31 /docker/abc $ROOT/cgroup/cpuset rw - cgroup cgroup rw,cpuset",
+			"36 32 0:32 /docker/abc $ROOT/cgroup/memory rw - cgroup cgroup rw,memory",
+			"40 32 0:36 /docker/abc $ROOT/cgroup/pids rw - cgroup cgroup rw,pids",
+			"42 32 0:38 / $ROOT/cgroup/unified rw - cgroup2 cgroup2 rw",
+		}, "\n"),
+		"proc/self/status":                         "Cpus_allowed_list:\t0-3\n",
+		"cgroup/cpu,cpuacct/cpu.cfs_quota_us":      "-1\n",
+		"cgroup/cpu,cpuacct/cpu.cfs_period_us":     "100000\n",
+		"cgroup/cpu,cpuacct/cpu.stat":              "nr_periods 0\nnr_throttled 0\nthrottled_time 0\n",
+		"cgroup/cpu,cpuacct/cpuacct.usage":         "2500000000\n",
+		"cgroup/memory/memory.limit_in_bytes":      "9223372036854771712\n",
+		"cgroup/memory/memory.usage_in_bytes":      "52428800\n",
+		"cgroup/pids/pids.max":                     "max\n",
+		"cgroup/pids/pids.current":                 "3\n",
+		"cgroup/unified/system.slice/containerd.service/memory.max": "1024\n",
+	})()
+
+	rl, err := Limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rl.Version != 1 || rl.Cgroup != "/docker/abc" {
+		t.Errorf("cgroup = %s v%d", rl.Cgroup, rl.Version)
+	}
+
+	// Unlimited, the cpuset falls back to the affinity of the process.
+	if rl.CPU.Quota != 0 || rl.CPU.Cores != 4 || len(rl.CPU.CPUSet) != 4 || rl.CPU.Usage != 2500 * time.Millisecond {
+		t.Errorf("cpu = %+v", rl.CPU)
+	}
+	if rl.Memory.Max != 0 || rl.Memory.Usage != 50 * MB || rl.Memory.UsedPercent() != 0 {
+		t.Errorf("memory = %+v", rl.Memory)
+	}
+	if rl.Pids.Max != 0 || rl.Pids.Current != 3 {
+		t.Errorf("pids = %+v", rl.Pids)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := map[string]uint64{
+		"max"                 : 0,
+		"-1"                  : 0,
+		"9223372036854771712" : 0,
+		"1048576"             : 1048576,
+	}
+	for s, want := range tests {
+		if got := parseLimit(s); got != want {
+			t.Errorf("parseLimit(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
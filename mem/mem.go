@@ -7,6 +7,7 @@ package mem
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/entuerto/sysmon"
 )
@@ -61,4 +62,258 @@ func (v Virtual) GoString() string {
 
 func VirtualMemory() (*Virtual, error) {
 	return virtualMemory()
-}
\ No newline at end of file
+}
+
+//---------------------------------------------------------------------------------------
+
+// NUMANode holds the memory usage of a NUMA node and its allocation
+// counters, counted in pages since boot.
+type NUMANode struct {
+	ID            int         `json:"id"`
+	CPUs          []int       `json:"cpus"`
+	Total         sysmon.Size `json:"total"`
+	Free          sysmon.Size `json:"free"`
+	Used          sysmon.Size `json:"used"`
+	FilePages     sysmon.Size `json:"filePages"`     // page cache
+	AnonPages     sysmon.Size `json:"anonPages"`
+	Percent       float64     `json:"percent"`       // used / total * 100
+	Hit           uint64      `json:"hit"`           // allocated on this node as intended
+	Miss          uint64      `json:"miss"`          // allocated on this node despite a preference for another
+	Foreign       uint64      `json:"foreign"`       // intended for this node but allocated on another
+	InterleaveHit uint64      `json:"interleaveHit"` // interleaved allocations that got this node
+	LocalNode     uint64      `json:"localNode"`     // allocated on this node by a process running on it
+	OtherNode     uint64      `json:"otherNode"`     // allocated on this node by a process running on another
+}
+
+func (n NUMANode) GoString() string {
+	s := []string{"NUMANode{",
+			fmt.Sprintf("  ID        : %d", n.ID),
+			fmt.Sprintf("  CPUs      : %v", n.CPUs),
+			fmt.Sprintf("  Total     : %s", n.Total),
+			fmt.Sprintf("  Free      : %s", n.Free),
+			fmt.Sprintf("  Used      : %s", n.Used),
+			fmt.Sprintf("  FilePages : %s", n.FilePages),
+			fmt.Sprintf("  AnonPages : %s", n.AnonPages),
+			fmt.Sprintf("  Percent   : %.2f", n.Percent),
+			fmt.Sprintf("  Hit       : %d", n.Hit),
+			fmt.Sprintf("  Miss      : %d", n.Miss),
+			fmt.Sprintf("  Foreign   : %d", n.Foreign),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// NUMANodes returns the memory of each NUMA node, none when the system has
+// no NUMA support.
+func NUMANodes() ([]*NUMANode, error) {
+	return numaNodes()
+}
+
+//---------------------------------------------------------------------------------------
+
+// VMStats holds the virtual memory event counters of the kernel since boot.
+// Reclaim is split between kswapd, the background reclaimer, and direct
+// reclaim, done by the allocating task itself and thus adding latency.
+type VMStats struct {
+	PageIn           uint64            `json:"pageIn"`           // KB read from disk
+	PageOut          uint64            `json:"pageOut"`          // KB written to disk
+	SwapIn           uint64            `json:"swapIn"`           // pages
+	SwapOut          uint64            `json:"swapOut"`          // pages
+	PageFaults       uint64            `json:"pageFaults"`       // minor and major
+	MajorFaults      uint64            `json:"majorFaults"`      // that needed I/O
+	ScanKswapd       uint64            `json:"scanKswapd"`       // pages scanned for reclaim
+	ScanDirect       uint64            `json:"scanDirect"`
+	StealKswapd      uint64            `json:"stealKswapd"`      // pages reclaimed
+	StealDirect      uint64            `json:"stealDirect"`
+	CompactStall     uint64            `json:"compactStall"`     // allocations that waited for compaction
+	CompactFail      uint64            `json:"compactFail"`
+	CompactSuccess   uint64            `json:"compactSuccess"`
+	THPFaultAlloc    uint64            `json:"thpFaultAlloc"`    // huge pages allocated on page fault
+	THPFaultFallback uint64            `json:"thpFaultFallback"` // faults that fell back to small pages
+	THPCollapseAlloc uint64            `json:"thpCollapseAlloc"` // huge pages assembled by khugepaged
+	OOMKill          uint64            `json:"oomKill"`          // processes killed by the OOM killer
+	Other            map[string]uint64 `json:"other"`            // the remaining counters by name
+	Time             time.Time         `json:"time"`             // when the counters were read
+}
+
+func (vs VMStats) GoString() string {
+	s := []string{"VMStats{",
+			fmt.Sprintf("  PageIn           : %d", vs.PageIn),
+			fmt.Sprintf("  PageOut          : %d", vs.PageOut),
+			fmt.Sprintf("  SwapIn           : %d", vs.SwapIn),
+			fmt.Sprintf("  SwapOut          : %d", vs.SwapOut),
+			fmt.Sprintf("  PageFaults       : %d", vs.PageFaults),
+			fmt.Sprintf("  MajorFaults      : %d", vs.MajorFaults),
+			fmt.Sprintf("  ScanKswapd       : %d", vs.ScanKswapd),
+			fmt.Sprintf("  ScanDirect       : %d", vs.ScanDirect),
+			fmt.Sprintf("  StealKswapd      : %d", vs.StealKswapd),
+			fmt.Sprintf("  StealDirect      : %d", vs.StealDirect),
+			fmt.Sprintf("  CompactStall     : %d", vs.CompactStall),
+			fmt.Sprintf("  CompactFail      : %d", vs.CompactFail),
+			fmt.Sprintf("  CompactSuccess   : %d", vs.CompactSuccess),
+			fmt.Sprintf("  THPFaultAlloc    : %d", vs.THPFaultAlloc),
+			fmt.Sprintf("  THPFaultFallback : %d", vs.THPFaultFallback),
+			fmt.Sprintf("  THPCollapseAlloc : %d", vs.THPCollapseAlloc),
+			fmt.Sprintf("  OOMKill          : %d", vs.OOMKill),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// VMRates holds the per second rates of the VMStats counters.
+type VMRates struct {
+	PageIn           float64            `json:"pageIn"`
+	PageOut          float64            `json:"pageOut"`
+	SwapIn           float64            `json:"swapIn"`
+	SwapOut          float64            `json:"swapOut"`
+	PageFaults       float64            `json:"pageFaults"`
+	MajorFaults      float64            `json:"majorFaults"`
+	ScanKswapd       float64            `json:"scanKswapd"`
+	ScanDirect       float64            `json:"scanDirect"`
+	StealKswapd      float64            `json:"stealKswapd"`
+	StealDirect      float64            `json:"stealDirect"`
+	CompactStall     float64            `json:"compactStall"`
+	CompactFail      float64            `json:"compactFail"`
+	CompactSuccess   float64            `json:"compactSuccess"`
+	THPFaultAlloc    float64            `json:"thpFaultAlloc"`
+	THPFaultFallback float64            `json:"thpFaultFallback"`
+	THPCollapseAlloc float64            `json:"thpCollapseAlloc"`
+	OOMKill          float64            `json:"oomKill"`
+	Other            map[string]float64 `json:"other"`
+	Interval         time.Duration      `json:"interval"` // time between the two samples
+}
+
+// vmCounters are the "nr_" entries of /proc/vmstat that count events instead
+// of holding a current value.
+var vmCounters = map[string]bool{
+	"nr_dirtied"                   : true,
+	"nr_written"                   : true,
+	"nr_throttled_written"         : true,
+	"nr_vmscan_write"              : true,
+	"nr_vmscan_immediate_reclaim"  : true,
+	"nr_foll_pin_acquired"         : true,
+	"nr_foll_pin_released"         : true,
+	"nr_tlb_remote_flush"          : true,
+	"nr_tlb_remote_flush_received" : true,
+	"nr_tlb_local_flush_all"       : true,
+	"nr_tlb_local_flush_one"       : true,
+}
+
+// vmGauges are the entries of /proc/vmstat without the "nr_" prefix that hold
+// a current value.
+var vmGauges = map[string]bool{
+	"workingset_nodes" : true,
+}
+
+// isVMGauge reports whether the /proc/vmstat entry name holds a current
+// value, such as nr_free_pages, rather than counting events.
+func isVMGauge(name string) bool {
+	if strings.HasPrefix(name, "nr_") {
+		return !vmCounters[name]
+	}
+	return vmGauges[name]
+}
+
+// Rates returns the per second rates of the counters between prev, an
+// earlier sample, and vs. Counters that went backwards count as 0. The
+// gauges of Other, such as nr_free_pages or workingset_nodes, are left out.
+// Without prev there are no rates.
+func (vs *VMStats) Rates(prev *VMStats) *VMRates {
+	r := &VMRates{
+		Other : make(map[string]float64),
+	}
+	if prev == nil {
+		return r
+	}
+
+	r.Interval = vs.Time.Sub(prev.Time)
+	if r.Interval <= 0 {
+		return r
+	}
+
+	secs := r.Interval.Seconds()
+	rate := func(cur, old uint64) float64 {
+		if cur < old {
+			return 0
+		}
+		return float64(cur - old) / secs
+	}
+
+	r.PageIn = rate(vs.PageIn, prev.PageIn)
+	r.PageOut = rate(vs.PageOut, prev.PageOut)
+	r.SwapIn = rate(vs.SwapIn, prev.SwapIn)
+	r.SwapOut = rate(vs.SwapOut, prev.SwapOut)
+	r.PageFaults = rate(vs.PageFaults, prev.PageFaults)
+	r.MajorFaults = rate(vs.MajorFaults, prev.MajorFaults)
+	r.ScanKswapd = rate(vs.ScanKswapd, prev.ScanKswapd)
+	r.ScanDirect = rate(vs.ScanDirect, prev.ScanDirect)
+	r.StealKswapd = rate(vs.StealKswapd, prev.StealKswapd)
+	r.StealDirect = rate(vs.StealDirect, prev.StealDirect)
+	r.CompactStall = rate(vs.CompactStall, prev.CompactStall)
+	r.CompactFail = rate(vs.CompactFail, prev.CompactFail)
+	r.CompactSuccess = rate(vs.CompactSuccess, prev.CompactSuccess)
+	r.THPFaultAlloc = rate(vs.THPFaultAlloc, prev.THPFaultAlloc)
+	r.THPFaultFallback = rate(vs.THPFaultFallback, prev.THPFaultFallback)
+	r.THPCollapseAlloc = rate(vs.THPCollapseAlloc, prev.THPCollapseAlloc)
+	r.OOMKill = rate(vs.OOMKill, prev.OOMKill)
+
+	for name, cur := range vs.Other {
+		if isVMGauge(name) {
+			continue
+		}
+		if old, ok := prev.Other[name]; ok {
+			r.Other[name] = rate(cur, old)
+		}
+	}
+	return r
+}
+
+// VMStat returns the virtual memory event counters.
+func VMStat() (*VMStats, error) {
+	return vmStat()
+}
+
+//---------------------------------------------------------------------------------------
+
+// HugePagePool is the pool of persistent huge pages of a size, counted in
+// pages.
+type HugePagePool struct {
+	Size     sysmon.Size `json:"size"`     // page size, such as 2 MB or 1 GB
+	Total    uint64      `json:"total"`    // pages in the pool, surplus included
+	Free     uint64      `json:"free"`     // pages not yet allocated, reserved included
+	Reserved uint64      `json:"reserved"` // pages promised to mappings but not yet faulted in
+	Surplus  uint64      `json:"surplus"`  // pages above the pool size, allocated by overcommit
+}
+
+func (hp HugePagePool) GoString() string {
+	s := []string{"HugePagePool{",
+			fmt.Sprintf("  Size     : %s", hp.Size),
+			fmt.Sprintf("  Total    : %d", hp.Total),
+			fmt.Sprintf("  Free     : %d", hp.Free),
+			fmt.Sprintf("  Reserved : %d", hp.Reserved),
+			fmt.Sprintf("  Surplus  : %d", hp.Surplus),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// Available returns the pages that can still be handed out, free and not
+// reserved.
+func (hp HugePagePool) Available() uint64 {
+	if hp.Reserved > hp.Free {
+		return 0
+	}
+	return hp.Free - hp.Reserved
+}
+
+type HugePageInfo struct {
+	Pools      []*HugePagePool `json:"pools"`      // by page size
+	THPEnabled string          `json:"thpEnabled"` // transparent huge pages: "always", "madvise" or "never", empty when not supported
+	THPDefrag  string          `json:"thpDefrag"`  // "always", "defer", "defer+madvise", "madvise" or "never"
+}
+
+// HugePages returns the huge page pools and the transparent huge page
+// settings.
+func HugePages() (*HugePageInfo, error) {
+	return hugePages()
+}
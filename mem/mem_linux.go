@@ -0,0 +1,287 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mem
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/entuerto/sysmon"
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+func swapMemory() (*Swap, error) {
+	mi, err := linux.ReadKeyValues(linux.ProcPath("meminfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	total := linux.ParseKB(mi["SwapTotal"])
+	free := linux.ParseKB(mi["SwapFree"])
+
+	sw := &Swap{
+		Total : sysmon.Size(total),
+		Used  : sysmon.Size(total - free),
+		Free  : sysmon.Size(free),
+	}
+	if total > 0 {
+		sw.Percent = float64(total - free) / float64(total) * 100
+	}
+
+	// Counted in pages.
+	vm, _ := readVMStat(linux.ProcPath("vmstat"))
+	pageSize := uint64(os.Getpagesize())
+	sw.SIn = sysmon.Size(vm["pswpin"] * pageSize)
+	sw.SOut = sysmon.Size(vm["pswpout"] * pageSize)
+	return sw, nil
+}
+
+func virtualMemory() (*Virtual, error) {
+	mi, err := linux.ReadKeyValues(linux.ProcPath("meminfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	total := linux.ParseKB(mi["MemTotal"])
+	free := linux.ParseKB(mi["MemFree"])
+
+	available := linux.ParseKB(mi["MemAvailable"])
+	if _, ok := mi["MemAvailable"]; !ok {
+		// Before Linux 3.14.
+		available = free + linux.ParseKB(mi["Buffers"]) + linux.ParseKB(mi["Cached"])
+	}
+
+	v := &Virtual{
+		Total     : sysmon.Size(total),
+		Available : sysmon.Size(available),
+		Used      : sysmon.Size(total - available),
+		Free      : sysmon.Size(free),
+	}
+	if total > 0 {
+		v.Percent = float64(total - available) / float64(total) * 100
+	}
+	return v, nil
+}
+
+//---------------------------------------------------------------------------------------
+
+func vmStat() (*VMStats, error) {
+	vm, err := readVMStat(linux.ProcPath("vmstat"))
+	if err != nil {
+		return nil, err
+	}
+	vs := parseVMStat(vm)
+	vs.Time = time.Now()
+	return vs, nil
+}
+
+// Zones of the per-zone reclaim counters before Linux 4.8, such as
+// pgscan_kswapd_normal.
+var vmZones = []string{"dma", "dma32", "normal", "high", "movable", "device"}
+
+// parseVMStat moves the well-known counters of vm into their fields, what
+// is left goes to Other.
+func parseVMStat(vm map[string]uint64) *VMStats {
+	take := func(name string) uint64 {
+		n := vm[name]
+		delete(vm, name)
+		return n
+	}
+	sumZones := func(name string) uint64 {
+		n := take(name)
+		for _, z := range vmZones {
+			n += take(name + "_" + z)
+		}
+		return n
+	}
+
+	return &VMStats{
+		PageIn           : take("pgpgin"),
+		PageOut          : take("pgpgout"),
+		SwapIn           : take("pswpin"),
+		SwapOut          : take("pswpout"),
+		PageFaults       : take("pgfault"),
+		MajorFaults      : take("pgmajfault"),
+		ScanKswapd       : sumZones("pgscan_kswapd"),
+		ScanDirect       : sumZones("pgscan_direct"),
+		StealKswapd      : sumZones("pgsteal_kswapd"),
+		StealDirect      : sumZones("pgsteal_direct"),
+		CompactStall     : take("compact_stall"),
+		CompactFail      : take("compact_fail"),
+		CompactSuccess   : take("compact_success"),
+		THPFaultAlloc    : take("thp_fault_alloc"),
+		THPFaultFallback : take("thp_fault_fallback"),
+		THPCollapseAlloc : take("thp_collapse_alloc"),
+		OOMKill          : take("oom_kill"),
+		Other            : vm,
+	}
+}
+
+//---------------------------------------------------------------------------------------
+
+func numaNodes() ([]*NUMANode, error) {
+	dir := linux.SysPath("devices", "system", "node")
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ret []*NUMANode
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "node") {
+			continue
+		}
+		id, err := strconv.Atoi(e.Name()[4:])
+		if err != nil {
+			continue
+		}
+
+		n, err := readNUMANode(dir + "/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		n.ID = id
+		ret = append(ret, n)
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret, nil
+}
+
+func readNUMANode(dir string) (*NUMANode, error) {
+	lines, err := linux.ReadLines(dir + "/meminfo")
+	if err != nil {
+		return nil, err
+	}
+
+	mi, err := parseNodeMeminfo(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &NUMANode{
+		Total     : sysmon.Size(mi["MemTotal"]),
+		Free      : sysmon.Size(mi["MemFree"]),
+		Used      : sysmon.Size(mi["MemUsed"]),
+		FilePages : sysmon.Size(mi["FilePages"]),
+		AnonPages : sysmon.Size(mi["AnonPages"]),
+	}
+	if n.Total > 0 {
+		n.Percent = float64(n.Used) / float64(n.Total) * 100
+	}
+
+	n.CPUs, _ = linux.ReadCPUList(dir + "/cpulist")
+
+	// Same "name value" format as /proc/vmstat.
+	st, _ := readVMStat(dir + "/numastat")
+	n.Hit = st["numa_hit"]
+	n.Miss = st["numa_miss"]
+	n.Foreign = st["numa_foreign"]
+	n.InterleaveHit = st["interleave_hit"]
+	n.LocalNode = st["local_node"]
+	n.OtherNode = st["other_node"]
+	return n, nil
+}
+
+// parseNodeMeminfo parses lines of the form "Node 0 MemTotal:  32817408 kB"
+// into bytes by key.
+func parseNodeMeminfo(lines []string) (map[string]uint64, error) {
+	ret := make(map[string]uint64, len(lines))
+
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+
+		f := strings.Fields(l)
+		if len(f) < 4 || f[0] != "Node" || !strings.HasSuffix(f[2], ":") {
+			return nil, fmt.Errorf("malformed node meminfo: %q", l)
+		}
+		ret[strings.TrimSuffix(f[2], ":")] = linux.ParseKB(strings.Join(f[3:], " "))
+	}
+	return ret, nil
+}
+
+// readVMStat reads files made of "name value" lines, such as /proc/vmstat.
+func readVMStat(path string) (map[string]uint64, error) {
+	lines, err := linux.ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]uint64, len(lines))
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseUint(f[1], 10, 64); err == nil {
+			ret[f[0]] = n
+		}
+	}
+	return ret, nil
+}
+
+//---------------------------------------------------------------------------------------
+
+func hugePages() (*HugePageInfo, error) {
+	dir := linux.SysPath("kernel", "mm", "hugepages")
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	hi := &HugePageInfo{}
+	for _, e := range entries {
+		// hugepages-2048kB
+		name := e.Name()
+		if !strings.HasPrefix(name, "hugepages-") || !strings.HasSuffix(name, "kB") {
+			continue
+		}
+		kb, err := strconv.ParseUint(name[len("hugepages-"):len(name)-2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		pool := dir + "/" + name
+		hp := &HugePagePool{Size: sysmon.Size(kb) * sysmon.KB}
+		hp.Total, _ = linux.ReadUint(pool + "/nr_hugepages")
+		hp.Free, _ = linux.ReadUint(pool + "/free_hugepages")
+		hp.Reserved, _ = linux.ReadUint(pool + "/resv_hugepages")
+		hp.Surplus, _ = linux.ReadUint(pool + "/surplus_hugepages")
+		hi.Pools = append(hi.Pools, hp)
+	}
+	sort.Slice(hi.Pools, func(i, j int) bool { return hi.Pools[i].Size < hi.Pools[j].Size })
+
+	thp := linux.SysPath("kernel", "mm", "transparent_hugepage")
+	if s, err := linux.ReadString(thp + "/enabled"); err == nil {
+		hi.THPEnabled = selectedMode(s)
+	}
+	if s, err := linux.ReadString(thp + "/defrag"); err == nil {
+		hi.THPDefrag = selectedMode(s)
+	}
+	return hi, nil
+}
+
+// selectedMode returns the bracketed choice of sysfs files such as
+// "always [madvise] never".
+func selectedMode(s string) string {
+	for _, f := range strings.Fields(s) {
+		if strings.HasPrefix(f, "[") && strings.HasSuffix(f, "]") {
+			return f[1:len(f)-1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,199 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mem
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/entuerto/sysmon"
+	"github.com/entuerto/sysmon/internal/linux/linuxtest"
+)
+
+func TestVirtualMemory(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{
+		"proc/meminfo": "MemTotal:        4194304 kB\nMemFree:         1048576 kB\nMemAvailable:    3145728 kB\nSwapTotal:       2097152 kB\nSwapFree:        1572864 kB\n",
+		"proc/vmstat":  "pswpin 10\npswpout 20\n",
+	})()
+
+	v, err := VirtualMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Total != 4 * sysmon.GB || v.Available != 3 * sysmon.GB || v.Used != sysmon.GB || v.Free != sysmon.GB || v.Percent != 25 {
+		t.Errorf("got %#v", v)
+	}
+
+	sw, err := SwapMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := sysmon.Size(os.Getpagesize())
+	if sw.Total != 2 * sysmon.GB || sw.Used != 512 * sysmon.MB || sw.Percent != 25 || sw.SIn != 10 * page || sw.SOut != 20 * page {
+		t.Errorf("got %#v", sw)
+	}
+}
+
+func TestNUMANodes(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{
+		"sys/devices/system/node/node0/cpulist": "0-3\n",
+		"sys/devices/system/node/node0/meminfo": "Node 0 MemTotal:        8388608 kB\nNode 0 MemFree:         2097152 kB\nNode 0 MemUsed:         6291456 kB\nNode 0 FilePages:       1048576 kB\nNode 0 AnonPages:       4194304 kB\nNode 0 HugePages_Total:     0\n",
+		"sys/devices/system/node/node0/numastat": "numa_hit 1000\nnuma_miss 10\nnuma_foreign 20\ninterleave_hit 5\nlocal_node 990\nother_node 10\n",
+		"sys/devices/system/node/node1/cpulist": "4-7\n",
+		"sys/devices/system/node/node1/meminfo": "Node 1 MemTotal:        8388608 kB\nNode 1 MemFree:         8388608 kB\nNode 1 MemUsed:               0 kB\n",
+		"sys/devices/system/node/node1/numastat": "numa_hit 50\nnuma_miss 20\nnuma_foreign 10\n",
+		"sys/devices/system/node/online": "0-1\n",
+	})()
+
+	nodes, err := NUMANodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("got %d nodes", len(nodes))
+	}
+
+	n := nodes[0]
+	if n.ID != 0 || len(n.CPUs) != 4 || n.Total != 8 * sysmon.GB || n.Free != 2 * sysmon.GB || n.Used != 6 * sysmon.GB || n.Percent != 75 {
+		t.Errorf("node 0 = %#v", n)
+	}
+	if n.FilePages != sysmon.GB || n.AnonPages != 4 * sysmon.GB {
+		t.Errorf("node 0 = %#v", n)
+	}
+	if n.Hit != 1000 || n.Miss != 10 || n.Foreign != 20 || n.InterleaveHit != 5 || n.LocalNode != 990 || n.OtherNode != 10 {
+		t.Errorf("node 0 = %#v", n)
+	}
+
+	if n := nodes[1]; n.ID != 1 || n.CPUs[0] != 4 || n.Percent != 0 || n.Miss != 20 {
+		t.Errorf("node 1 = %#v", n)
+	}
+
+	if _, err := parseNodeMeminfo([]string{"MemTotal: 1 kB"}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestNoNUMA(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{"sys/devices/system/cpu/online": "0\n"})()
+
+	nodes, err := NUMANodes()
+	if err != nil || len(nodes) != 0 {
+		t.Errorf("NUMANodes() = %v, %v, want none", nodes, err)
+	}
+}
+
+func TestVMStat(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{
+		"proc/vmstat": "nr_free_pages 822359\npgpgin 1000\npgpgout 2000\npswpin 1\npswpout 2\npgfault 500000\npgmajfault 300\n" +
+			"pgsteal_kswapd 40\npgsteal_direct 4\npgscan_kswapd 100\npgscan_direct 10\npgscan_direct_throttle 1\n" +
+			"compact_stall 7\ncompact_fail 2\ncompact_success 5\nthp_fault_alloc 20\nthp_fault_fallback 3\nthp_collapse_alloc 6\noom_kill 1\nworkingset_refault_file 9\n",
+	})()
+
+	vs, err := VMStat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vs.PageIn != 1000 || vs.PageOut != 2000 || vs.SwapIn != 1 || vs.SwapOut != 2 || vs.PageFaults != 500000 || vs.MajorFaults != 300 {
+		t.Errorf("got %#v", vs)
+	}
+	if vs.ScanKswapd != 100 || vs.ScanDirect != 10 || vs.StealKswapd != 40 || vs.StealDirect != 4 {
+		t.Errorf("got %#v", vs)
+	}
+	if vs.CompactStall != 7 || vs.CompactFail != 2 || vs.CompactSuccess != 5 || vs.THPFaultAlloc != 20 || vs.THPFaultFallback != 3 || vs.THPCollapseAlloc != 6 || vs.OOMKill != 1 {
+		t.Errorf("got %#v", vs)
+	}
+	if len(vs.Other) != 3 || vs.Other["pgscan_direct_throttle"] != 1 || vs.Other["nr_free_pages"] != 822359 {
+		t.Errorf("other = %v", vs.Other)
+	}
+	if vs.Time.IsZero() {
+		t.Error("time not set")
+	}
+}
+
+func TestParseVMStatZones(t *testing.T) {
+	// Linux before 4.8 counts reclaim per zone.
+	vs := parseVMStat(map[string]uint64{
+		"pgscan_kswapd_dma32"   : 5,
+		"pgscan_kswapd_normal"  : 10,
+		"pgscan_kswapd_movable" : 1,
+		"pgsteal_direct_normal" : 3,
+	})
+	if vs.ScanKswapd != 16 || vs.StealDirect != 3 || len(vs.Other) != 0 {
+		t.Errorf("got %#v, other = %v", vs, vs.Other)
+	}
+}
+
+func TestVMRates(t *testing.T) {
+	now := time.Now()
+	prev := &VMStats{
+		PageFaults : 1000,
+		OOMKill    : 2,
+		Other      : map[string]uint64{"nr_free_pages": 100, "nr_dirtied": 40, "workingset_nodes": 7, "workingset_refault_file": 10},
+		Time       : now,
+	}
+	cur := &VMStats{
+		PageFaults : 3000,
+		OOMKill    : 1, // counters reset
+		Other      : map[string]uint64{"nr_free_pages": 50, "nr_dirtied": 60, "workingset_nodes": 9, "workingset_refault_file": 30, "new_counter": 1},
+		Time       : now.Add(2 * time.Second),
+	}
+
+	r := cur.Rates(prev)
+	if r.Interval != 2 * time.Second || r.PageFaults != 1000 || r.OOMKill != 0 {
+		t.Errorf("got %+v", r)
+	}
+	if len(r.Other) != 2 || r.Other["workingset_refault_file"] != 10 || r.Other["nr_dirtied"] != 10 {
+		t.Errorf("other = %v", r.Other)
+	}
+
+	if r := prev.Rates(prev); r.PageFaults != 0 {
+		t.Errorf("got %+v for a zero interval", r)
+	}
+	if r := cur.Rates(nil); r.Interval != 0 || r.PageFaults != 0 || len(r.Other) != 0 {
+		t.Errorf("got %+v without a previous sample", r)
+	}
+}
+
+func TestHugePages(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{
+		"sys/kernel/mm/hugepages/hugepages-2048kB/nr_hugepages":         "1024\n",
+		"sys/kernel/mm/hugepages/hugepages-2048kB/free_hugepages":       "512\n",
+		"sys/kernel/mm/hugepages/hugepages-2048kB/resv_hugepages":       "128\n",
+		"sys/kernel/mm/hugepages/hugepages-2048kB/surplus_hugepages":    "0\n",
+		"sys/kernel/mm/hugepages/hugepages-1048576kB/nr_hugepages":      "4\n",
+		"sys/kernel/mm/hugepages/hugepages-1048576kB/free_hugepages":    "4\n",
+		"sys/kernel/mm/hugepages/hugepages-1048576kB/resv_hugepages":    "0\n",
+		"sys/kernel/mm/hugepages/hugepages-1048576kB/surplus_hugepages": "0\n",
+		"sys/kernel/mm/transparent_hugepage/enabled":                    "always [madvise] never\n",
+		"sys/kernel/mm/transparent_hugepage/defrag":                     "always defer defer+madvise [madvise] never\n",
+	})()
+
+	hi, err := HugePages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hi.Pools) != 2 {
+		t.Fatalf("got %d pools", len(hi.Pools))
+	}
+	if hp := hi.Pools[0]; hp.Size != 2 * sysmon.MB || hp.Total != 1024 || hp.Free != 512 || hp.Reserved != 128 || hp.Available() != 384 {
+		t.Errorf("pool = %#v", hp)
+	}
+	if hp := hi.Pools[1]; hp.Size != sysmon.GB || hp.Total != 4 || hp.Available() != 4 {
+		t.Errorf("pool = %#v", hp)
+	}
+	if hi.THPEnabled != "madvise" || hi.THPDefrag != "madvise" {
+		t.Errorf("thp = %q, %q", hi.THPEnabled, hi.THPDefrag)
+	}
+}
+
+func TestNoHugePages(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{"sys/kernel/mm/ksm/run": "0\n"})()
+
+	hi, err := HugePages()
+	if err != nil || len(hi.Pools) != 0 || hi.THPEnabled != "" {
+		t.Errorf("HugePages() = %#v, %v, want none", hi, err)
+	}
+}
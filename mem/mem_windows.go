@@ -5,6 +5,7 @@
 package mem
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -28,6 +29,18 @@ func swapMemory() (*Swap, error) {
 	}, nil
 }
 
+func numaNodes() ([]*NUMANode, error) {
+	return nil, errors.New("NUMA nodes not supported on windows")
+}
+
+func vmStat() (*VMStats, error) {
+	return nil, errors.New("vmstat not supported on windows")
+}
+
+func hugePages() (*HugePageInfo, error) {
+	return nil, errors.New("huge pages not supported on windows")
+}
+
 func virtualMemory() (*Virtual, error) {
 	mem, err := win32.GlobalMemoryStatusEx() 
 	if err != nil {
@@ -0,0 +1,99 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sysmon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PressureResource is a resource tracked by Pressure Stall Information.
+type PressureResource string
+
+const (
+	PressureCPU    PressureResource = "cpu"
+	PressureMemory PressureResource = "memory"
+	PressureIO     PressureResource = "io"
+	PressureIRQ    PressureResource = "irq" // Linux 6.1 with CONFIG_IRQ_TIME_ACCOUNTING, full only
+)
+
+// ErrNoPSI is returned when the kernel has no Pressure Stall Information,
+// either not built in or disabled with psi=0.
+var ErrNoPSI = errors.New("pressure stall information not available")
+
+type PressureStats struct {
+	Avg10  float64       `json:"avg10"`  // share of time stalled, in percent, over 10 seconds
+	Avg60  float64       `json:"avg60"`
+	Avg300 float64       `json:"avg300"`
+	Total  time.Duration `json:"total"`  // cumulative stall time
+}
+
+// Pressure tells how much time tasks were stalled waiting for a resource.
+// Some is the time at least one task was stalled, Full the time all non-idle
+// tasks were stalled at once. Full is zero for the CPU before Linux 5.13.
+type Pressure struct {
+	Resource PressureResource `json:"resource"`
+	Some     PressureStats    `json:"some"`
+	Full     PressureStats    `json:"full"`
+}
+
+func (p Pressure) GoString() string {
+	s := []string{"Pressure{",
+			fmt.Sprintf("  Resource : %s", p.Resource),
+			fmt.Sprintf("  Some     : avg10=%.2f avg60=%.2f avg300=%.2f total=%s", p.Some.Avg10, p.Some.Avg60, p.Some.Avg300, p.Some.Total),
+			fmt.Sprintf("  Full     : avg10=%.2f avg60=%.2f avg300=%.2f total=%s", p.Full.Avg10, p.Full.Avg60, p.Full.Avg300, p.Full.Total),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// ReadPressure returns the system wide pressure of resource, from
+// /proc/pressure. It needs Linux 4.20 with CONFIG_PSI.
+func ReadPressure(resource PressureResource) (*Pressure, error) {
+	return systemPressure(resource)
+}
+
+// CgroupPressure returns the pressure of resource on the tasks of a cgroup v2
+// path, for example "/system.slice/sshd.service".
+func CgroupPressure(cgroup string, resource PressureResource) (*Pressure, error) {
+	return cgroupPressure(cgroup, resource)
+}
+
+//---------------------------------------------------------------------------------------
+
+// PressureTrigger asks for an event when tasks stall on a resource for more
+// than Stall within any Window.
+type PressureTrigger struct {
+	Resource PressureResource `json:"resource"`
+	Full     bool             `json:"full"`   // all non-idle tasks stalled, instead of some
+	Stall    time.Duration    `json:"stall"`  // stall budget
+	Window   time.Duration    `json:"window"` // from 500ms to 10s, a multiple of 2s for unprivileged users
+	Cgroup   string           `json:"cgroup"` // cgroup v2 path, empty for the whole system
+}
+
+func (pt PressureTrigger) String() string {
+	kind := "some"
+	if pt.Full {
+		kind = "full"
+	}
+	// The kernel wants microseconds.
+	return fmt.Sprintf("%s %d %d", kind, pt.Stall / time.Microsecond, pt.Window / time.Microsecond)
+}
+
+type PressureEvent struct {
+	Trigger  PressureTrigger `json:"trigger"`
+	Time     time.Time       `json:"time"`
+	Pressure *Pressure       `json:"pressure"` // read right after the event, nil when it failed
+}
+
+// WatchPressure registers trigger with the kernel and sends an event each
+// time the stall budget is exceeded, at most once per window. The channel is
+// closed when ctx is done or the cgroup is removed.
+func WatchPressure(ctx context.Context, trigger PressureTrigger) (<-chan PressureEvent, error) {
+	return watchPressure(ctx, trigger)
+}
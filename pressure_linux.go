@@ -0,0 +1,195 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sysmon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+func systemPressure(resource PressureResource) (*Pressure, error) {
+	p, err := readPressure(linux.ProcPath("pressure", string(resource)), resource)
+	if err != nil && noPSI(err) {
+		return nil, ErrNoPSI
+	}
+	return p, err
+}
+
+func noPSI(err error) bool {
+	if os.IsNotExist(err) {
+		_, err := os.Stat(linux.ProcPath("pressure"))
+		return os.IsNotExist(err)
+	}
+	if pe, ok := err.(*os.PathError); ok {
+		return pe.Err == syscall.EOPNOTSUPP
+	}
+	return false
+}
+
+func cgroupPressure(cgroup string, resource PressureResource) (*Pressure, error) {
+	path, err := cgroupPressurePath(cgroup, resource)
+	if err != nil {
+		return nil, err
+	}
+	return readPressure(path, resource)
+}
+
+func readPressure(path string, resource PressureResource) (*Pressure, error) {
+	lines, err := linux.ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := parsePressure(lines)
+	if err != nil {
+		return nil, err
+	}
+	p.Resource = resource
+	return p, nil
+}
+
+// parsePressure parses lines of the form
+// "some avg10=0.00 avg60=0.00 avg300=0.00 total=0", total in microseconds.
+func parsePressure(lines []string) (*Pressure, error) {
+	p := &Pressure{}
+
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) == 0 {
+			continue
+		}
+
+		var ps *PressureStats
+		switch f[0] {
+		case "some":
+			ps = &p.Some
+		case "full":
+			ps = &p.Full
+		default:
+			return nil, fmt.Errorf("malformed pressure: %q", l)
+		}
+
+		for _, kv := range f[1:] {
+			i := strings.IndexByte(kv, '=')
+			if i < 0 {
+				return nil, fmt.Errorf("malformed pressure: %q", l)
+			}
+
+			key, value := kv[:i], kv[i+1:]
+			if key == "total" {
+				us, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("malformed pressure: %q", l)
+				}
+				ps.Total = time.Duration(us) * time.Microsecond
+				continue
+			}
+
+			avg, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed pressure: %q", l)
+			}
+			switch key {
+			case "avg10":
+				ps.Avg10 = avg
+			case "avg60":
+				ps.Avg60 = avg
+			case "avg300":
+				ps.Avg300 = avg
+			}
+		}
+	}
+	return p, nil
+}
+
+// cgroupPressurePath returns the path of the <resource>.pressure file of a
+// cgroup in the mounted cgroup v2 hierarchy.
+func cgroupPressurePath(cgroup string, resource PressureResource) (string, error) {
+	mounts, err := linux.ReadCgroupMounts()
+	if err != nil {
+		return "", err
+	}
+
+	for _, m := range mounts {
+		if m.Version != 2 {
+			continue
+		}
+		if dir := m.Path(cgroup); dir != "" {
+			return dir + "/" + string(resource) + ".pressure", nil
+		}
+	}
+	return "", fmt.Errorf("cgroup %s not found in a cgroup v2 hierarchy", cgroup)
+}
+
+//---------------------------------------------------------------------------------------
+
+func watchPressure(ctx context.Context, trigger PressureTrigger) (<-chan PressureEvent, error) {
+	path := linux.ProcPath("pressure", string(trigger.Resource))
+	if trigger.Cgroup != "" {
+		var err error
+		if path, err = cgroupPressurePath(trigger.Cgroup, trigger.Resource); err != nil {
+			return nil, err
+		}
+	}
+
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	// The trigger lives as long as fd. The kernel overwrites the last byte
+	// written with a NUL, so it has to be one.
+	if _, err := syscall.Write(fd, []byte(trigger.String() + "\x00")); err != nil {
+		syscall.Close(fd)
+		return nil, &os.PathError{Op: "write", Path: path, Err: err}
+	}
+
+	ch := make(chan PressureEvent)
+	go func() {
+		defer close(ch)
+		defer syscall.Close(fd)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			// Wake up regularly to check whether ctx is done.
+			ev, err := linux.Poll(fd, linux.POLLPRI, 100*time.Millisecond)
+			if err != nil && err != syscall.EINTR {
+				return
+			}
+			if ev&linux.POLLERR != 0 {
+				// The cgroup was removed.
+				return
+			}
+			if ev&linux.POLLPRI == 0 {
+				continue
+			}
+
+			pe := PressureEvent{
+				Trigger : trigger,
+				Time    : time.Now(),
+			}
+			pe.Pressure, _ = readPressure(path, trigger.Resource)
+
+			select {
+			case ch <- pe:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
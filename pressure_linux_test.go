@@ -0,0 +1,66 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sysmon
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParsePressure(t *testing.T) {
+	p, err := parsePressure([]string{
+		"some avg10=1.57 avg60=1.33 avg300=1.24 total=24331905",
+		"full avg10=0.00 avg60=0.43 avg300=6.21 total=57579999",
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if p.Some.Avg10 != 1.57 || p.Some.Avg60 != 1.33 || p.Some.Avg300 != 1.24 || p.Some.Total != 24331905 * time.Microsecond {
+		t.Errorf("some = %+v", p.Some)
+	}
+	if p.Full.Avg60 != 0.43 || p.Full.Total != 57579999 * time.Microsecond {
+		t.Errorf("full = %+v", p.Full)
+	}
+
+	if _, err := parsePressure([]string{"some avg10=x"}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestPressureTrigger(t *testing.T) {
+	pt := PressureTrigger{
+		Resource : PressureMemory,
+		Full     : true,
+		Stall    : 150 * time.Millisecond,
+		Window   : time.Second,
+	}
+	if s := pt.String(); s != "full 150000 1000000" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestWatchPressure(t *testing.T) {
+	if _, err := ReadPressure(PressureCPU); err != nil {
+		t.Skipf("no PSI: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := WatchPressure(ctx, PressureTrigger{Resource: PressureCPU, Stall: 100 * time.Millisecond, Window: 2 * time.Second})
+	if err != nil {
+		t.Skipf("cannot register trigger: %v", err)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		for ok {
+			_, ok = <-ch
+		}
+	case <-time.After(time.Second):
+		t.Error("channel not closed after cancel")
+	}
+}
@@ -5,10 +5,21 @@
 package proc
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"syscall"
+	"time"
+
+	"github.com/entuerto/sysmon"
 )
 
+// Processes returns the running processes the caller is allowed to query.
+// On Windows each process holds a handle that should be freed with Release.
+func Processes() ([]*Process, error) {
+	return processes()
+}
+
 // ProcessesByName()
 
 type Process struct {
@@ -22,7 +33,8 @@ type Process struct {
 	Status      string
 	UserName    string
 
-	handle uintptr  // windows proof
+	handle    uintptr  // process handle on windows, pidfd on linux
+	startTime uint64   // clock ticks after boot on linux, FILETIME on windows
 }
 
 func (p Process) GoString() string {
@@ -41,6 +53,30 @@ func (p Process) GoString() string {
 	return strings.Join(s, "\n")	
 }
 
+// ProcessKey identifies a process over time. Unlike the pid alone it is
+// never shared by a process that reuses the pid of an exited one.
+type ProcessKey struct {
+	Pid       uint32 `json:"pid"`
+	StartTime uint64 `json:"startTime"` // platform specific start time
+}
+
+func (k ProcessKey) String() string {
+	return fmt.Sprintf("%d@%d", k.Pid, k.StartTime)
+}
+
+// Key returns the identity key of the process.
+func (p Process) Key() ProcessKey {
+	return ProcessKey{
+		Pid       : p.Pid,
+		StartTime : p.startTime,
+	}
+}
+
+// Age returns the time elapsed since the process started.
+func (p Process) Age() (time.Duration, error) {
+	return p.age()
+}
+
 func (p Process) Parent() (*Process, error) {
 	return OpenProcess(p.ParentId)
 }
@@ -65,4 +101,202 @@ func (p Process) Threads() ([]*Thread, error) {
 	return p.threads()
 }
 
-// runtime.SetFinalizer(p, (*Process).Release)
\ No newline at end of file
+// Release frees the handle held on the process. On Linux the handle is a
+// pidfd, on Windows a process handle.
+func (p Process) Release() error {
+	return p.release()
+}
+
+// runtime.SetFinalizer(p, (*Process).Release)
+
+//---------------------------------------------------------------------------------------
+
+type ExitStatus struct {
+	Pid    uint32         `json:"pid"`
+	Code   int            `json:"code"`   // Exit code, -1 when the process was killed by a signal or 
+	                                      // is not a child of the caller and its status is unknown.
+	Signal syscall.Signal `json:"signal"` // The signal that terminated the process, 0 if none.
+}
+
+func (es ExitStatus) GoString() string {
+	s := []string{"ExitStatus{", 
+			fmt.Sprintf("  Pid    : %d", es.Pid), 
+			fmt.Sprintf("  Code   : %d", es.Code), 
+			fmt.Sprintf("  Signal : %v", es.Signal), 
+			"}",
+	}
+	return strings.Join(s, "\n")	
+}
+
+// Signal sends sig to the process. On Windows only SIGKILL and SIGTERM are
+// supported, both terminate the process.
+func (p Process) Signal(sig syscall.Signal) error {
+	return p.signal(sig)
+}
+
+// Kill forcibly stops the process.
+func (p Process) Kill() error {
+	return p.signal(syscall.SIGKILL)
+}
+
+// Terminate asks the process to exit and waits up to timeout for it to do 
+// so. If it is still running after timeout the process is killed.
+func (p Process) Terminate(timeout time.Duration) (*ExitStatus, error) {
+	if err := p.signal(syscall.SIGTERM); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	es, err := p.wait(ctx)
+	if err != context.DeadlineExceeded {
+		return es, err
+	}
+
+	if err := p.signal(syscall.SIGKILL); err != nil {
+		return nil, err
+	}
+	return p.wait(context.Background())
+}
+
+// Suspend stops all threads of the process.
+func (p Process) Suspend() error {
+	return p.suspend()
+}
+
+// Resume continues a process stopped by Suspend.
+func (p Process) Resume() error {
+	return p.resume()
+}
+
+// Wait blocks until the process exits or ctx is done. The exit code can 
+// only be collected for children of the calling process.
+func (p Process) Wait(ctx context.Context) (*ExitStatus, error) {
+	return p.wait(ctx)
+}
+
+//---------------------------------------------------------------------------------------
+
+type IOCounters struct  {
+	ReadCount  uint64      `json:"readCount"`
+	WriteCount uint64      `json:"writeCount"`
+	OtherCount uint64      `json:"otherCount"`
+	ReadBytes  sysmon.Size `json:"readBytes"`
+	WriteBytes sysmon.Size `json:"writeBytes"`
+	OtherBytes sysmon.Size `json:"otherBytes"`
+}
+
+func (ioc IOCounters) GoString() string {
+	s := []string{"IOCounters{", 
+			fmt.Sprintf("  ReadCount  : %d", ioc.ReadCount), 
+			fmt.Sprintf("  WriteCount : %d", ioc.WriteCount), 
+			fmt.Sprintf("  OtherCount : %d", ioc.OtherCount), 
+			fmt.Sprintf("  ReadBytes  : %s", ioc.ReadBytes), 
+			fmt.Sprintf("  WriteBytes : %s", ioc.WriteBytes), 
+			fmt.Sprintf("  OtherBytes : %s", ioc.OtherBytes), 
+			"}",
+	}
+	return strings.Join(s, "\n")	
+}
+
+
+//---------------------------------------------------------------------------------------
+
+type TimeUsage struct {
+	CreationTime time.Time     `json:"creationTime"`
+	ExitTime     time.Time     `json:"exitTime"`
+	KernelTime   time.Duration `json:"kernelTime"`
+	UserTime     time.Duration `json:"userTime"`
+}
+
+func (tu TimeUsage) GoString() string {
+	s := []string{"TimeUsage{", 
+			fmt.Sprintf("  CreationTime : %s", tu.CreationTime), 
+			fmt.Sprintf("  ExitTime     : %s", tu.ExitTime), 
+			fmt.Sprintf("  KernelTime   : %s", tu.KernelTime), 
+			fmt.Sprintf("  UserTime     : %s", tu.UserTime),  
+			"}",
+	}
+	return strings.Join(s, "\n")	
+}
+
+
+//---------------------------------------------------------------------------------------
+
+type MemoryCounters struct {
+	PageFaultCount             uint32      // The number of page faults.
+	PeakWorkingSetSize         sysmon.Size // The peak working set size, in bytes.
+	WorkingSetSize             sysmon.Size // The current working set size, in bytes.
+	QuotaPeakPagedPoolUsage    sysmon.Size // The peak paged pool usage, in bytes.
+	QuotaPagedPoolUsage        sysmon.Size // The current paged pool usage, in bytes.
+	QuotaPeakNonPagedPoolUsage sysmon.Size // The peak nonpaged pool usage, in bytes.
+	QuotaNonPagedPoolUsage     sysmon.Size // The current nonpaged pool usage, in bytes.
+	PagefileUsage              sysmon.Size // The Commit Charge value in bytes for this process. Commit Charge 
+	                                       // is the total amount of memory that the memory manager has committed 
+	                                       // for a running process.
+	PeakPagefileUsage          sysmon.Size // The peak value in bytes of the Commit Charge during the lifetime 
+	                                       // of this process.
+}
+
+func (mc MemoryCounters) GoString() string {
+	s := []string{"MemoryCounters{", 
+			fmt.Sprintf("  PageFaultCount             : %d", mc.PageFaultCount),   
+			fmt.Sprintf("  PeakWorkingSetSize         : %s", mc.PeakWorkingSetSize),   
+			fmt.Sprintf("  WorkingSetSize             : %s", mc.WorkingSetSize),   
+			fmt.Sprintf("  QuotaPeakPagedPoolUsage    : %s", mc.QuotaPeakPagedPoolUsage),   
+			fmt.Sprintf("  QuotaPagedPoolUsage        : %s", mc.QuotaPagedPoolUsage),   
+			fmt.Sprintf("  QuotaPeakNonPagedPoolUsage : %s", mc.QuotaPeakNonPagedPoolUsage),   
+			fmt.Sprintf("  QuotaNonPagedPoolUsage     : %s", mc.QuotaNonPagedPoolUsage),   
+			fmt.Sprintf("  PagefileUsage              : %s", mc.PagefileUsage),   
+			fmt.Sprintf("  PeakPagefileUsage          : %s", mc.PeakPagefileUsage),   
+			"}",
+	}
+	return strings.Join(s, "\n")	
+}
+
+
+//---------------------------------------------------------------------------------------
+
+type Module struct {
+	ProcessID uint32
+	BaseAddr  uintptr      // The base address of the module in the context of the owning process.
+	BaseSize  sysmon.Size  // The size of the module, in bytes.
+	Handle    uintptr      // A handle to the module in the context of the owning process.
+	Name      string
+	ExePath   string
+}
+
+func (m Module) GoString() string {
+	s := []string{"Module{", 
+			fmt.Sprintf("  ProcessID : %v", m.ProcessID),   
+			fmt.Sprintf("  BaseAddr  : %x", m.BaseAddr),   
+			fmt.Sprintf("  BaseSize  : %s", m.BaseSize),   
+			fmt.Sprintf("  Handle    : %v", m.Handle),   
+			fmt.Sprintf("  Name      : %s", m.Name),   
+			fmt.Sprintf("  ExePath   : %s", m.ExePath),   
+			"}",
+	}
+	return strings.Join(s, "\n")	
+}
+
+
+//---------------------------------------------------------------------------------------
+
+type Thread struct {
+	ThreadID        uint32
+	OwnerProcessID  uint32
+	BasePriority    int32  // The kernel base priority level assigned to the thread. 
+	                       // The priority is a number from 0 to 31, with 0 representing 
+	                       // the lowest possible thread priority.
+}
+
+func (t Thread) GoString() string {
+	s := []string{"Thread{", 
+			fmt.Sprintf("  ThreadID       : %v", t.ThreadID),   
+			fmt.Sprintf("  OwnerProcessID : %v", t.OwnerProcessID),   
+			fmt.Sprintf("  BasePriority   : %v", t.BasePriority),   
+			"}",
+	}
+	return strings.Join(s, "\n")	
+}
@@ -0,0 +1,467 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/entuerto/sysmon"
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// Values of Process.Status on Linux.
+const (
+	StatusRunning     = "running"
+	StatusSleeping    = "sleeping"
+	StatusDiskSleep   = "disk-sleep"
+	StatusStopped     = "stopped"
+	StatusTracingStop = "tracing-stop"
+	StatusZombie      = "zombie"
+	StatusDead        = "dead"
+	StatusIdle        = "idle"
+	StatusParked      = "parked"
+)
+
+var statusNames = map[byte]string{
+	'R': StatusRunning,
+	'S': StatusSleeping,
+	'D': StatusDiskSleep,
+	'T': StatusStopped,
+	't': StatusTracingStop,
+	'Z': StatusZombie,
+	'X': StatusDead,
+	'x': StatusDead,
+	'I': StatusIdle,
+	'P': StatusParked,
+}
+
+// OpenProcess returns the process pid. The process is referred to by a
+// pidfd, so operations sending signals never reach a process that reused
+// the pid after this one exited.
+func OpenProcess(pid uint32) (*Process, error) {
+	// Open the pidfd first so that /proc is read for the process it refers to.
+	fd, err := linux.PidfdOpen(pid)
+	if err != nil && err != syscall.ENOSYS {
+		return nil, os.NewSyscallError("pidfd_open", err)
+	}
+
+	p, err := newProcess(pid)
+	if err != nil {
+		if fd >= 0 {
+			syscall.Close(fd)
+		}
+		return nil, err
+	}
+
+	if fd >= 0 {
+		p.handle = uintptr(fd)
+	}
+	return p, nil
+}
+
+// processes lists /proc. Unlike OpenProcess no pidfd is held, one is opened
+// for the duration of each operation that needs it.
+func processes() ([]*Process, error) {
+	pids, err := linux.Pids()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Process
+	for _, pid := range pids {
+		p, err := newProcess(pid)
+		if err != nil {
+			// The process exited since /proc was read.
+			continue
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+// newProcess reads the information of pid from /proc without opening a pidfd.
+func newProcess(pid uint32) (*Process, error) {
+	st, err := linux.ReadStat(linux.PidPath(pid, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Kernel threads have no command line nor executable.
+	exe, _ := os.Readlink(linux.PidPath(pid, "exe"))
+
+	cmdLine, _ := ioutil.ReadFile(linux.PidPath(pid, "cmdline"))
+	args := strings.Split(strings.TrimRight(string(cmdLine), "\x00"), "\x00")
+
+	// Only readable for processes of the same user.
+	fds, _ := linux.CountEntries(linux.PidPath(pid, "fd"))
+
+	var userName string
+	if kv, err := linux.ReadKeyValues(linux.PidPath(pid, "status")); err == nil {
+		if uid, err := parseIDs(kv["Uid"]); err == nil {
+			userName = users.User(uid.Effective)
+		}
+	}
+
+	return &Process{
+		Pid        : pid,
+		ParentId   : st.PPid,
+		Name       : st.Comm,
+		Executable : exe,
+		CmdLine    : strings.Join(args, " "),
+		HandleCount: uint32(fds),
+		ThreadCount: uint32(st.NumThreads),
+		Status     : statusNames[st.State],
+		UserName   : userName,
+		startTime  : st.StartTime,
+	}, nil
+}
+
+func (p Process) age() (time.Duration, error) {
+	if p.startTime == 0 {
+		st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+		if err != nil {
+			return 0, err
+		}
+		p.startTime = st.StartTime
+	}
+	// Measured against the uptime so that changes of the wall clock do not
+	// matter. The uptime is truncated to 10ms, so a new process may come out
+	// slightly younger than 0.
+	age := sysmon.UpTime() - linux.TicksToDuration(p.startTime)
+	if age < 0 {
+		age = 0
+	}
+	return age, nil
+}
+
+func (p Process) release() error {
+	if p.handle == 0 {
+		return nil
+	}
+	return syscall.Close(int(p.handle))
+}
+
+// pidfd returns the pidfd held by p or opens a new one, in which case the
+// returned function closes it. It returns -1 on kernels without pidfd.
+// A new pidfd is only returned if it refers to the process identified by
+// p.Key(), not to a process that reused the pid.
+func (p Process) pidfd() (int, func(), error) {
+	if p.handle != 0 {
+		return int(p.handle), func() {}, nil
+	}
+
+	fd, err := linux.PidfdOpen(p.Pid)
+	if err != nil && err != syscall.ENOSYS {
+		return -1, nil, os.NewSyscallError("pidfd_open", err)
+	}
+
+	if err := p.checkStartTime("pidfd_open"); err != nil {
+		if fd >= 0 {
+			syscall.Close(fd)
+		}
+		return -1, nil, err
+	}
+
+	if fd < 0 {
+		return -1, func() {}, nil
+	}
+	return fd, func() { syscall.Close(fd) }, nil
+}
+
+// checkStartTime fails with ESRCH, reported as coming from call, when the pid
+// now belongs to a process started at another time than p.
+func (p Process) checkStartTime(call string) error {
+	if p.startTime == 0 {
+		return nil
+	}
+	st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+	if err != nil || st.StartTime != p.startTime {
+		return os.NewSyscallError(call, syscall.ESRCH)
+	}
+	return nil
+}
+
+func (p Process) signal(sig syscall.Signal) error {
+	fd, done, err := p.pidfd()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if fd < 0 {
+		return os.NewSyscallError("kill", syscall.Kill(int(p.Pid), sig))
+	}
+	return os.NewSyscallError("pidfd_send_signal", linux.PidfdSendSignal(fd, sig))
+}
+
+func (p Process) suspend() error {
+	return p.signal(syscall.SIGSTOP)
+}
+
+func (p Process) resume() error {
+	return p.signal(syscall.SIGCONT)
+}
+
+func (p Process) wait(ctx context.Context) (*ExitStatus, error) {
+	fd, done, err := p.pidfd()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	for {
+		exited, err := p.exited(fd)
+		if err != nil {
+			return nil, err
+		}
+		if exited {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+	}
+
+	es := &ExitStatus{
+		Pid  : p.Pid,
+		Code : -1,
+	}
+
+	if fd < 0 {
+		return p.wait4(es)
+	}
+
+	// Only children can be reaped, others exit silently.
+	info, err := linux.WaitidPidfd(fd, syscall.WEXITED | syscall.WNOHANG)
+	if err == syscall.ECHILD {
+		return es, nil
+	}
+	if err != nil {
+		return nil, os.NewSyscallError("waitid", err)
+	}
+
+	switch info.Code {
+	case linux.CLD_EXITED:
+		es.Code = int(info.Status)
+	case linux.CLD_KILLED, linux.CLD_DUMPED:
+		es.Signal = syscall.Signal(info.Status)
+	}
+	return es, nil
+}
+
+// exited waits a short while for the process to exit. A pidfd becomes
+// readable when the process terminates.
+func (p Process) exited(fd int) (bool, error) {
+	const interval = 100 * time.Millisecond
+
+	if fd < 0 {
+		// Without a pidfd the pid is polled. A zombie has exited, and a pid
+		// with another start time belongs to a new process.
+		st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if st.State == 'Z' || (p.startTime != 0 && st.StartTime != p.startTime) {
+			return true, nil
+		}
+		time.Sleep(interval)
+		return false, nil
+	}
+
+	ev, err := linux.Poll(fd, linux.POLLIN, interval)
+	if err == syscall.EINTR {
+		return false, nil
+	}
+	if err != nil {
+		return false, os.NewSyscallError("ppoll", err)
+	}
+	return ev != 0, nil
+}
+
+// wait4 reaps p once exited saw it terminate without a pidfd. Only a zombie
+// with the start time of p is reaped, the pid may belong to another child by
+// now.
+func (p Process) wait4(es *ExitStatus) (*ExitStatus, error) {
+	st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+	if err != nil || st.State != 'Z' || (p.startTime != 0 && st.StartTime != p.startTime) {
+		return es, nil
+	}
+
+	// Only children can be reaped, others exit silently.
+	var ws syscall.WaitStatus
+	_, err = syscall.Wait4(int(p.Pid), &ws, syscall.WNOHANG, nil)
+	if err == syscall.ECHILD {
+		return es, nil
+	}
+	if err != nil {
+		return nil, os.NewSyscallError("wait4", err)
+	}
+
+	switch {
+	case ws.Exited():
+		es.Code = ws.ExitStatus()
+	case ws.Signaled():
+		es.Signal = ws.Signal()
+	}
+	return es, nil
+}
+
+//---------------------------------------------------------------------------------------
+
+func (p Process) ioCounters() (*IOCounters, error) {
+	kv, err := linux.ReadKeyValues(linux.PidPath(p.Pid, "io"))
+	if err != nil {
+		return nil, err
+	}
+
+	u := func(key string) uint64 {
+		n, _ := strconv.ParseUint(kv[key], 10, 64)
+		return n
+	}
+
+	return &IOCounters{
+		ReadCount  : u("syscr"),
+		WriteCount : u("syscw"),
+		ReadBytes  : sysmon.Size(u("read_bytes")),
+		WriteBytes : sysmon.Size(u("write_bytes")),
+	}, nil
+}
+
+func (p Process) usage() (*TimeUsage, error) {
+	st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	boot, err := linux.BootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TimeUsage{
+		CreationTime : boot.Add(linux.TicksToDuration(st.StartTime)),
+		KernelTime   : linux.TicksToDuration(st.STime),
+		UserTime     : linux.TicksToDuration(st.UTime),
+	}, nil
+}
+
+// memoryInfo maps /proc/<pid>/status to the Windows counters. The paged and
+// nonpaged pool quotas have no Linux equivalent.
+func (p Process) memoryInfo() (*MemoryCounters, error) {
+	st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := linux.ReadKeyValues(linux.PidPath(p.Pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryCounters{
+		PageFaultCount     : uint32(st.MinFlt + st.MajFlt),
+		PeakWorkingSetSize : sysmon.Size(linux.ParseKB(kv["VmHWM"])),
+		WorkingSetSize     : sysmon.Size(linux.ParseKB(kv["VmRSS"])),
+		PagefileUsage      : sysmon.Size(linux.ParseKB(kv["VmSize"])),
+		PeakPagefileUsage  : sysmon.Size(linux.ParseKB(kv["VmPeak"])),
+	}, nil
+}
+
+// modules lists the executable file mappings of the process, the main
+// executable excluded.
+func (p Process) modules() ([]*Module, error) {
+	lines, err := linux.ReadLines(linux.PidPath(p.Pid, "maps"))
+	if err != nil {
+		return nil, err
+	}
+
+	type span struct {
+		start, end uint64
+		exec       bool
+	}
+
+	var order []string
+	spans := make(map[string]*span)
+
+	for _, l := range lines {
+		// address perms offset dev inode pathname
+		f := strings.Fields(l)
+		if len(f) < 6 || !strings.HasPrefix(f[5], "/") {
+			continue
+		}
+
+		addr := strings.SplitN(f[0], "-", 2)
+		start, _ := strconv.ParseUint(addr[0], 16, 64)
+		end, _ := strconv.ParseUint(addr[1], 16, 64)
+
+		path := strings.Join(f[5:], " ")
+		s, ok := spans[path]
+		if !ok {
+			s = &span{start: start, end: end}
+			spans[path] = s
+			order = append(order, path)
+		}
+		if start < s.start {
+			s.start = start
+		}
+		if end > s.end {
+			s.end = end
+		}
+		s.exec = s.exec || strings.Contains(f[1], "x")
+	}
+
+	var ret []*Module
+	for _, path := range order {
+		s := spans[path]
+		if !s.exec || path == p.Executable {
+			continue
+		}
+
+		ret = append(ret, &Module{
+			ProcessID : p.Pid,
+			BaseAddr  : uintptr(s.start),
+			BaseSize  : sysmon.Size(s.end - s.start),
+			Name      : filepath.Base(path),
+			ExePath   : path,
+		})
+	}
+	return ret, nil
+}
+
+func (p Process) threads() ([]*Thread, error) {
+	tids, err := linux.Tids(p.Pid)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Thread
+	for _, tid := range tids {
+		st, err := linux.ReadStat(linux.TidPath(p.Pid, tid, "stat"))
+		if err != nil {
+			// The thread exited since the directory was read.
+			continue
+		}
+
+		ret = append(ret, &Thread{
+			ThreadID       : tid,
+			OwnerProcessID : p.Pid,
+			BasePriority   : int32(st.Priority),
+		})
+	}
+	return ret, nil
+}
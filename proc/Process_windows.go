@@ -5,10 +5,10 @@
 package proc
 
 import (
+	"context"
 	"fmt"
 //	"log"
 	"os"
-	"strings"
 	"syscall"
 	"time"
 	"unsafe"
@@ -40,6 +40,12 @@ func OpenProcess(pid uint32) (*Process, error) {
 		return nil, err
 	}
 
+	var u syscall.Rusage
+	err = syscall.GetProcessTimes(h, &u.CreationTime, &u.ExitTime, &u.KernelTime, &u.UserTime)
+	if err != nil {
+		return nil, os.NewSyscallError("GetProcessTimes", err)
+	}
+
 	return &Process{
 		Pid        : pid,
 		handle     : uintptr(h),
@@ -49,33 +55,129 @@ func OpenProcess(pid uint32) (*Process, error) {
 		CmdLine    : syscall.UTF16ToString(modEntry.ExePath[:]),
 		HandleCount: handleCount,
 		ThreadCount: procEntry.Threads,
+		startTime  : uint64(u.CreationTime.HighDateTime) << 32 | uint64(u.CreationTime.LowDateTime),
 	}, nil
 }
 
-//---------------------------------------------------------------------------------------
+func processes() ([]*Process, error) {
+	snapshot, err := win32.CreateToolhelp32Snapshot(win32.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var procEntry win32.ProcessEntry32
+	procEntry.Size = uint32(unsafe.Sizeof(procEntry))
+
+	if err = win32.Process32First(snapshot, &procEntry); err != nil {
+		return nil, err
+	}
+
+	var ret []*Process
+	for {
+		// System processes cannot be opened without privileges.
+		if p, err := OpenProcess(procEntry.ProcessID); err == nil {
+			ret = append(ret, p)
+		}
+
+		err = win32.Process32Next(snapshot, &procEntry)
+		if err != nil {
+			if err == syscall.ERROR_NO_MORE_FILES {
+				break
+			}
+			return ret, err
+		}
+	}
+	return ret, nil
+}
 
-type IOCounters struct  {
-	ReadCount  uint64      `json:"readCount"`
-	WriteCount uint64      `json:"writeCount"`
-	OtherCount uint64      `json:"otherCount"`
-	ReadBytes  sysmon.Size `json:"readBytes"`
-	WriteBytes sysmon.Size `json:"writeBytes"`
-	OtherBytes sysmon.Size `json:"otherBytes"`
+func (p Process) age() (time.Duration, error) {
+	u, err := p.usage()
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(u.CreationTime), nil
 }
 
-func (ioc IOCounters) GoString() string {
-	s := []string{"IOCounters{", 
-			fmt.Sprintf("  ReadCount  : %d", ioc.ReadCount), 
-			fmt.Sprintf("  WriteCount : %d", ioc.WriteCount), 
-			fmt.Sprintf("  OtherCount : %d", ioc.OtherCount), 
-			fmt.Sprintf("  ReadBytes  : %s", ioc.ReadBytes), 
-			fmt.Sprintf("  WriteBytes : %s", ioc.WriteBytes), 
-			fmt.Sprintf("  OtherBytes : %s", ioc.OtherBytes), 
-			"}",
+func (p Process) release() error {
+	if p.handle == 0 {
+		return nil
 	}
-	return strings.Join(s, "\n")	
+	return syscall.CloseHandle(syscall.Handle(p.handle))
+}
+
+// withAccess opens a handle with the given access rights for the duration
+// of fn. The handle held by p keeps the process object alive, so its pid 
+// cannot be reused in the meantime.
+func (p Process) withAccess(da uint32, fn func(h syscall.Handle) error) error {
+	h, err := syscall.OpenProcess(da, false, p.Pid)
+	if err != nil {
+		return os.NewSyscallError("OpenProcess", err)
+	}
+	defer syscall.CloseHandle(h)
+
+	return fn(h)
+}
+
+func (p Process) signal(sig syscall.Signal) error {
+	if sig != syscall.SIGKILL && sig != syscall.SIGTERM {
+		return fmt.Errorf("signal %v not supported on windows", sig)
+	}
+
+	return p.withAccess(syscall.PROCESS_TERMINATE, func(h syscall.Handle) error {
+		return os.NewSyscallError("TerminateProcess", syscall.TerminateProcess(h, 1))
+	})
+}
+
+func (p Process) suspend() error {
+	return p.withAccess(win32.PROCESS_SUSPEND_RESUME, win32.NtSuspendProcess)
+}
+
+func (p Process) resume() error {
+	return p.withAccess(win32.PROCESS_SUSPEND_RESUME, win32.NtResumeProcess)
 }
 
+func (p Process) wait(ctx context.Context) (*ExitStatus, error) {
+	h := syscall.Handle(p.handle)
+	if h == 0 {
+		var err error
+		h, err = syscall.OpenProcess(syscall.SYNCHRONIZE | syscall.PROCESS_QUERY_INFORMATION, false, p.Pid)
+		if err != nil {
+			return nil, os.NewSyscallError("OpenProcess", err)
+		}
+		defer syscall.CloseHandle(h)
+	}
+
+	for {
+		// Wake up regularly to check whether ctx is done.
+		ev, err := syscall.WaitForSingleObject(h, 100)
+		if err != nil {
+			return nil, os.NewSyscallError("WaitForSingleObject", err)
+		}
+		if ev == syscall.WAIT_OBJECT_0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+	}
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return nil, os.NewSyscallError("GetExitCodeProcess", err)
+	}
+
+	return &ExitStatus{
+		Pid  : p.Pid,
+		Code : int(code),
+	}, nil
+}
+
+//---------------------------------------------------------------------------------------
+
 func (p Process) ioCounters() (*IOCounters, error){
 	wioc, err := win32.GetProcessIoCounters(syscall.Handle(p.handle)) 
 	if err != nil {
@@ -94,24 +196,6 @@ func (p Process) ioCounters() (*IOCounters, error){
 
 //---------------------------------------------------------------------------------------
 
-type TimeUsage struct {
-	CreationTime time.Time     `json:"creationTime"`
-	ExitTime     time.Time     `json:"exitTime"`
-	KernelTime   time.Duration `json:"kernelTime"`
-	UserTime     time.Duration `json:"userTime"`
-}
-
-func (tu TimeUsage) GoString() string {
-	s := []string{"TimeUsage{", 
-			fmt.Sprintf("  CreationTime : %s", tu.CreationTime), 
-			fmt.Sprintf("  ExitTime     : %s", tu.ExitTime), 
-			fmt.Sprintf("  KernelTime   : %s", tu.KernelTime), 
-			fmt.Sprintf("  UserTime     : %s", tu.UserTime),  
-			"}",
-	}
-	return strings.Join(s, "\n")	
-}
-
 func (p Process) usage() (*TimeUsage, error) {
 	var u syscall.Rusage
 
@@ -130,37 +214,6 @@ func (p Process) usage() (*TimeUsage, error) {
 
 //---------------------------------------------------------------------------------------
 
-type MemoryCounters struct {
-	PageFaultCount             uint32      // The number of page faults.
-	PeakWorkingSetSize         sysmon.Size // The peak working set size, in bytes.
-	WorkingSetSize             sysmon.Size // The current working set size, in bytes.
-	QuotaPeakPagedPoolUsage    sysmon.Size // The peak paged pool usage, in bytes.
-	QuotaPagedPoolUsage        sysmon.Size // The current paged pool usage, in bytes.
-	QuotaPeakNonPagedPoolUsage sysmon.Size // The peak nonpaged pool usage, in bytes.
-	QuotaNonPagedPoolUsage     sysmon.Size // The current nonpaged pool usage, in bytes.
-	PagefileUsage              sysmon.Size // The Commit Charge value in bytes for this process. Commit Charge 
-	                                       // is the total amount of memory that the memory manager has committed 
-	                                       // for a running process.
-	PeakPagefileUsage          sysmon.Size // The peak value in bytes of the Commit Charge during the lifetime 
-	                                       // of this process.
-}
-
-func (mc MemoryCounters) GoString() string {
-	s := []string{"MemoryCounters{", 
-			fmt.Sprintf("  PageFaultCount             : %d", mc.PageFaultCount),   
-			fmt.Sprintf("  PeakWorkingSetSize         : %s", mc.PeakWorkingSetSize),   
-			fmt.Sprintf("  WorkingSetSize             : %s", mc.WorkingSetSize),   
-			fmt.Sprintf("  QuotaPeakPagedPoolUsage    : %s", mc.QuotaPeakPagedPoolUsage),   
-			fmt.Sprintf("  QuotaPagedPoolUsage        : %s", mc.QuotaPagedPoolUsage),   
-			fmt.Sprintf("  QuotaPeakNonPagedPoolUsage : %s", mc.QuotaPeakNonPagedPoolUsage),   
-			fmt.Sprintf("  QuotaNonPagedPoolUsage     : %s", mc.QuotaNonPagedPoolUsage),   
-			fmt.Sprintf("  PagefileUsage              : %s", mc.PagefileUsage),   
-			fmt.Sprintf("  PeakPagefileUsage          : %s", mc.PeakPagefileUsage),   
-			"}",
-	}
-	return strings.Join(s, "\n")	
-}
-
 func (p Process) memoryInfo() (*MemoryCounters, error) {
 	pmc, err := win32.GetProcessMemoryInfo(syscall.Handle(p.handle)) 
 	if err != nil {
@@ -182,28 +235,6 @@ func (p Process) memoryInfo() (*MemoryCounters, error) {
 
 //---------------------------------------------------------------------------------------
 
-type Module struct {
-	ProcessID uint32
-	BaseAddr  uintptr      // The base address of the module in the context of the owning process.
-	BaseSize  sysmon.Size  // The size of the module, in bytes.
-	Handle    uintptr      // A handle to the module in the context of the owning process.
-	Name      string
-	ExePath   string
-}
-
-func (m Module) GoString() string {
-	s := []string{"Module{", 
-			fmt.Sprintf("  ProcessID : %v", m.ProcessID),   
-			fmt.Sprintf("  BaseAddr  : %x", m.BaseAddr),   
-			fmt.Sprintf("  BaseSize  : %s", m.BaseSize),   
-			fmt.Sprintf("  Handle    : %v", m.Handle),   
-			fmt.Sprintf("  Name      : %s", m.Name),   
-			fmt.Sprintf("  ExePath   : %s", m.ExePath),   
-			"}",
-	}
-	return strings.Join(s, "\n")	
-}
-
 func (p Process) modules() ([]*Module, error) {
 	var ret []*Module
 
@@ -244,24 +275,6 @@ func (p Process) modules() ([]*Module, error) {
 
 //---------------------------------------------------------------------------------------
 
-type Thread struct {
-	ThreadID        uint32
-	OwnerProcessID  uint32
-	BasePriority    int32  // The kernel base priority level assigned to the thread. 
-	                       // The priority is a number from 0 to 31, with 0 representing 
-	                       // the lowest possible thread priority.
-}
-
-func (t Thread) GoString() string {
-	s := []string{"Thread{", 
-			fmt.Sprintf("  ThreadID       : %v", t.ThreadID),   
-			fmt.Sprintf("  OwnerProcessID : %v", t.OwnerProcessID),   
-			fmt.Sprintf("  BasePriority   : %v", t.BasePriority),   
-			"}",
-	}
-	return strings.Join(s, "\n")	
-}
-
 func (p Process) threads() ([]*Thread, error) {
 	var ret []*Thread
 
@@ -298,6 +311,11 @@ func (p Process) threads() ([]*Thread, error) {
 	return ret, nil
 }
 
+// Windows has no cgroups, selectors on cgroups never match.
+func cgroupPaths(p *Process) []string {
+	return nil
+}
+
 //---------------------------------------------------------------------------------------
 
 func toDuration(ft syscall.Filetime) time.Duration {
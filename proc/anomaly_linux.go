@@ -0,0 +1,229 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+type AnomalyKind int
+
+const (
+	AnomalyZombie    AnomalyKind = iota + 1 // exited, but not reaped by its parent
+	AnomalyOrphan                           // re-parented to init or a subreaper
+	AnomalyDiskSleep                        // stuck in uninterruptible sleep
+)
+
+func (ak AnomalyKind) String() string {
+	switch ak {
+	case AnomalyZombie:
+		return "zombie"
+	case AnomalyOrphan:
+		return "orphan"
+	case AnomalyDiskSleep:
+		return "disk-sleep"
+	}
+	return fmt.Sprintf("AnomalyKind(%d)", int(ak))
+}
+
+// LongDiskSleep is how long a process must stay in uninterruptible sleep
+// before Anomalies reports it.
+var LongDiskSleep = 30 * time.Second
+
+//---------------------------------------------------------------------------------------
+
+type Anomaly struct {
+	Kind     AnomalyKind   `json:"kind"`
+	Process  *Process      `json:"process"`
+	Since    time.Time     `json:"since"`    // first time Anomalies saw the process in this state
+	Duration time.Duration `json:"duration"` // time since Since, a lower bound of the time in this state
+}
+
+func (a Anomaly) GoString() string {
+	s := []string{"Anomaly{",
+			fmt.Sprintf("  Kind     : %s", a.Kind),
+			fmt.Sprintf("  Pid      : %d", a.Process.Pid),
+			fmt.Sprintf("  Name     : %s", a.Process.Name),
+			fmt.Sprintf("  ParentId : %d", a.Process.ParentId),
+			fmt.Sprintf("  Since    : %s", a.Since),
+			fmt.Sprintf("  Duration : %s", a.Duration),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// ZombieGroup holds the zombies of a parent that does not reap them.
+type ZombieGroup struct {
+	Parent  *Process   `json:"parent"` // nil when the parent could not be read
+	Zombies []*Anomaly `json:"zombies"`
+}
+
+type AnomalyReport struct {
+	Zombies   []*ZombieGroup `json:"zombies"`   // by parent pid
+	Orphans   []*Anomaly     `json:"orphans"`   // by pid
+	DiskSleep []*Anomaly     `json:"diskSleep"` // by pid, only those asleep for at least LongDiskSleep
+}
+
+// Anomalies looks for zombie processes, orphaned processes and processes
+// stuck in uninterruptible sleep.
+//
+// The kernel does not record when a process entered a state, so durations
+// are measured from the first call of Anomalies that saw the process in the
+// state. Call it periodically for the durations to be meaningful.
+//
+// A process whose parent is init, the init of a pid namespace or a systemd
+// instance (a subreaper) is an orphan when a previous call saw it with
+// another parent, or when it belongs to the process group of another
+// process. It is reported until it leaves the reaper. Daemons lead their own
+// process group, so they are not reported.
+func Anomalies() (*AnomalyReport, error) {
+	procs, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+	return anomalies.report(procs, time.Now()), nil
+}
+
+//---------------------------------------------------------------------------------------
+
+type anomalyKey struct {
+	key  ProcessKey
+	kind AnomalyKind
+}
+
+// anomalyTracker remembers when processes were first seen in an abnormal
+// state and the last known parent of every process.
+type anomalyTracker struct {
+	mu      sync.Mutex
+	since   map[anomalyKey]time.Time
+	parents map[ProcessKey]uint32
+}
+
+var anomalies = &anomalyTracker{}
+
+func (at *anomalyTracker) report(procs []*Process, now time.Time) *AnomalyReport {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	byPid := make(map[uint32]*Process, len(procs))
+	for _, p := range procs {
+		byPid[p.Pid] = p
+	}
+
+	since := make(map[anomalyKey]time.Time)
+	found := func(kind AnomalyKind, p *Process) *Anomaly {
+		k := anomalyKey{p.Key(), kind}
+		t, ok := at.since[k]
+		if !ok {
+			t = now
+		}
+		since[k] = t
+		return &Anomaly{
+			Kind     : kind,
+			Process  : p,
+			Since    : t,
+			Duration : now.Sub(t),
+		}
+	}
+
+	rep := &AnomalyReport{}
+	zombies := make(map[uint32]*ZombieGroup)
+	reapers := make(map[uint32]bool)
+	parents := make(map[ProcessKey]uint32, len(procs))
+
+	for _, p := range procs {
+		parents[p.Key()] = p.ParentId
+
+		switch p.Status {
+		case StatusZombie:
+			zg, ok := zombies[p.ParentId]
+			if !ok {
+				zg = &ZombieGroup{Parent: byPid[p.ParentId]}
+				zombies[p.ParentId] = zg
+				rep.Zombies = append(rep.Zombies, zg)
+			}
+			zg.Zombies = append(zg.Zombies, found(AnomalyZombie, p))
+			continue
+
+		case StatusDiskSleep:
+			if a := found(AnomalyDiskSleep, p); a.Duration >= LongDiskSleep {
+				rep.DiskSleep = append(rep.DiskSleep, a)
+			}
+		}
+
+		reaper, ok := reapers[p.ParentId]
+		if !ok {
+			reaper = isReaper(byPid[p.ParentId])
+			reapers[p.ParentId] = reaper
+		}
+		if reaper && at.orphaned(p) {
+			rep.Orphans = append(rep.Orphans, found(AnomalyOrphan, p))
+		}
+	}
+
+	// Forget the processes that are gone or back to normal.
+	at.since = since
+	at.parents = parents
+
+	sort.Slice(rep.Zombies, func(i, j int) bool {
+		return parentOf(rep.Zombies[i]) < parentOf(rep.Zombies[j])
+	})
+	for _, zg := range rep.Zombies {
+		sortAnomalies(zg.Zombies)
+	}
+	sortAnomalies(rep.Orphans)
+	sortAnomalies(rep.DiskSleep)
+	return rep
+}
+
+// orphaned reports whether p, whose parent is a reaper, did not start there.
+// An orphan stays one as long as it is adopted, the previous parent is only
+// seen on the call right after the re-parenting.
+func (at *anomalyTracker) orphaned(p *Process) bool {
+	if _, ok := at.since[anomalyKey{p.Key(), AnomalyOrphan}]; ok {
+		return true
+	}
+	if prev, ok := at.parents[p.Key()]; ok && prev != p.ParentId {
+		return true
+	}
+
+	// Still in the process group of the process that started it.
+	st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+	if err != nil {
+		return false
+	}
+	pgrp := uint32(st.Pgrp)
+	return pgrp != p.Pid && pgrp != p.ParentId && pgrp != 0
+}
+
+// isReaper reports whether p adopts the orphans of its descendants. Only
+// init processes are known for sure, subreapers are guessed by name as the
+// kernel does not expose the flag.
+func isReaper(p *Process) bool {
+	if p == nil {
+		return false
+	}
+	if p.Pid == 1 || p.Name == "systemd" {
+		return true
+	}
+	nspid, err := p.NamespacePid()
+	return err == nil && nspid == 1
+}
+
+func parentOf(zg *ZombieGroup) uint32 {
+	return zg.Zombies[0].Process.ParentId
+}
+
+func sortAnomalies(as []*Anomaly) {
+	sort.Slice(as, func(i, j int) bool {
+		return as[i].Process.Pid < as[j].Process.Pid
+	})
+}
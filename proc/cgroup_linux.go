@@ -0,0 +1,109 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+type Cgroup struct {
+	HierarchyID int      `json:"hierarchyId"` // 0 for the cgroup v2 unified hierarchy
+	Controllers []string `json:"controllers"` // empty for cgroup v2
+	Path        string   `json:"path"`        // relative to the mount point of the hierarchy
+}
+
+func (cg Cgroup) GoString() string {
+	s := []string{"Cgroup{",
+			fmt.Sprintf("  HierarchyID : %d", cg.HierarchyID),
+			fmt.Sprintf("  Controllers : %v", cg.Controllers),
+			fmt.Sprintf("  Path        : %s", cg.Path),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// IsUnified reports whether the entry is the cgroup v2 hierarchy.
+func (cg Cgroup) IsUnified() bool {
+	return cg.HierarchyID == 0 && len(cg.Controllers) == 0
+}
+
+// Cgroups returns the control groups of the process, one per mounted
+// hierarchy for cgroup v1 and a single unified entry for cgroup v2.
+func (p Process) Cgroups() ([]*Cgroup, error) {
+	entries, err := linux.ReadCgroups(linux.PidPath(p.Pid, "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Cgroup
+	for _, e := range entries {
+		ret = append(ret, &Cgroup{
+			HierarchyID : e.HierarchyID,
+			Controllers : e.Controllers,
+			Path        : e.Path,
+		})
+	}
+	return ret, nil
+}
+
+// ContainerID returns the id of the container the process runs in and the
+// runtime that created it: "docker", "containerd", "cri-o", "podman" or
+// "kubepods". Both are empty when the process is not in a container.
+func (p Process) ContainerID() (id, runtime string, err error) {
+	cgroups, err := p.Cgroups()
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, cg := range cgroups {
+		if id, runtime = linux.ContainerID(cg.Path); id != "" {
+			return id, runtime, nil
+		}
+	}
+	return "", "", nil
+}
+
+// ProcessesInCgroup returns the processes that belong to the cgroup path in
+// any hierarchy, for example "/system.slice/sshd.service".
+func ProcessesInCgroup(path string) ([]*Process, error) {
+	procs, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Process
+	for _, p := range procs {
+		cgroups, err := p.Cgroups()
+		if err != nil {
+			// The process exited since it was listed.
+			continue
+		}
+
+		for _, cg := range cgroups {
+			if cg.Path == path {
+				ret = append(ret, p)
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+// cgroupPaths returns the cgroup paths of p in all hierarchies.
+func cgroupPaths(p *Process) []string {
+	cgroups, err := p.Cgroups()
+	if err != nil {
+		return nil
+	}
+
+	var ret []string
+	for _, cg := range cgroups {
+		ret = append(ret, cg.Path)
+	}
+	return ret
+}
@@ -0,0 +1,124 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// IDs holds the four user or group ids of a process, see credentials(7).
+type IDs struct {
+	Real       uint32 `json:"real"`
+	Effective  uint32 `json:"effective"`  // Used for most permission checks.
+	Saved      uint32 `json:"saved"`      // Restorable by a set-user-ID program.
+	FileSystem uint32 `json:"fileSystem"` // Used for file system permission checks.
+}
+
+// Names holds the names matching IDs.
+type Names struct {
+	Real       string `json:"real"`
+	Effective  string `json:"effective"`
+	Saved      string `json:"saved"`
+	FileSystem string `json:"fileSystem"`
+}
+
+type Credentials struct {
+	UID        IDs      `json:"uid"`
+	GID        IDs      `json:"gid"`
+	Groups     []uint32 `json:"groups"` // supplementary group ids
+	UserNames  Names    `json:"userNames"`
+	GroupNames Names    `json:"groupNames"`
+	GroupList  []string `json:"groupList"` // supplementary group names
+}
+
+func (c Credentials) GoString() string {
+	s := []string{"Credentials{",
+			fmt.Sprintf("  UID        : %+v", c.UID),
+			fmt.Sprintf("  GID        : %+v", c.GID),
+			fmt.Sprintf("  Groups     : %v", c.Groups),
+			fmt.Sprintf("  UserNames  : %+v", c.UserNames),
+			fmt.Sprintf("  GroupNames : %+v", c.GroupNames),
+			fmt.Sprintf("  GroupList  : %v", c.GroupList),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// Credentials returns the user and group ids of the process with their
+// names, resolved from /etc/passwd and /etc/group.
+func (p Process) Credentials() (*Credentials, error) {
+	kv, err := linux.ReadKeyValues(linux.PidPath(p.Pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+	return parseCredentials(kv)
+}
+
+func parseCredentials(kv map[string]string) (*Credentials, error) {
+	uid, err := parseIDs(kv["Uid"])
+	if err != nil {
+		return nil, err
+	}
+	gid, err := parseIDs(kv["Gid"])
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Credentials{
+		UID : uid,
+		GID : gid,
+		UserNames : Names{
+			Real       : users.User(uid.Real),
+			Effective  : users.User(uid.Effective),
+			Saved      : users.User(uid.Saved),
+			FileSystem : users.User(uid.FileSystem),
+		},
+		GroupNames : Names{
+			Real       : users.Group(gid.Real),
+			Effective  : users.Group(gid.Effective),
+			Saved      : users.Group(gid.Saved),
+			FileSystem : users.Group(gid.FileSystem),
+		},
+	}
+
+	for _, f := range strings.Fields(kv["Groups"]) {
+		g, err := strconv.ParseUint(f, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed Groups: %q", kv["Groups"])
+		}
+		c.Groups = append(c.Groups, uint32(g))
+		c.GroupList = append(c.GroupList, users.Group(uint32(g)))
+	}
+	return c, nil
+}
+
+// parseIDs parses the Uid and Gid lines of /proc/<pid>/status: real,
+// effective, saved and file system ids.
+func parseIDs(s string) (IDs, error) {
+	f := strings.Fields(s)
+	if len(f) != 4 {
+		return IDs{}, fmt.Errorf("malformed ids: %q", s)
+	}
+
+	var v [4]uint32
+	for i := range v {
+		n, err := strconv.ParseUint(f[i], 10, 32)
+		if err != nil {
+			return IDs{}, fmt.Errorf("malformed ids: %q", s)
+		}
+		v[i] = uint32(n)
+	}
+
+	return IDs{
+		Real       : v[0],
+		Effective  : v[1],
+		Saved      : v[2],
+		FileSystem : v[3],
+	}, nil
+}
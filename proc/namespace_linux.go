@@ -0,0 +1,139 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// NamespaceKind is the type of a Linux namespace, named after the entries
+// of /proc/<pid>/ns.
+type NamespaceKind string
+
+const (
+	NamespaceCgroup NamespaceKind = "cgroup"
+	NamespaceIPC    NamespaceKind = "ipc"
+	NamespaceMount  NamespaceKind = "mnt"
+	NamespaceNet    NamespaceKind = "net"
+	NamespacePID    NamespaceKind = "pid"
+	NamespaceTime   NamespaceKind = "time"
+	NamespaceUser   NamespaceKind = "user"
+	NamespaceUTS    NamespaceKind = "uts"
+)
+
+var namespaceKinds = []NamespaceKind{
+	NamespaceCgroup,
+	NamespaceIPC,
+	NamespaceMount,
+	NamespaceNet,
+	NamespacePID,
+	NamespaceTime,
+	NamespaceUser,
+	NamespaceUTS,
+}
+
+// Namespaces returns the inode number identifying each namespace of the
+// process. Two processes are in the same namespace when the numbers match.
+// Kinds not supported by the running kernel are left out. Reading the
+// namespaces of another user's process requires CAP_SYS_PTRACE.
+func (p Process) Namespaces() (map[NamespaceKind]uint64, error) {
+	// A missing entry is a kind the kernel does not have, as long as the
+	// process is still there.
+	if _, err := os.Stat(linux.PidPath(p.Pid, "ns")); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[NamespaceKind]uint64, len(namespaceKinds))
+
+	for _, kind := range namespaceKinds {
+		ino, err := p.namespace(kind)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret[kind] = ino
+	}
+	return ret, nil
+}
+
+func (p Process) namespace(kind NamespaceKind) (uint64, error) {
+	link, err := os.Readlink(linux.PidPath(p.Pid, "ns", string(kind)))
+	if err != nil {
+		return 0, err
+	}
+	return parseNamespaceLink(link)
+}
+
+// parseNamespaceLink parses links such as "net:[4026531992]".
+func parseNamespaceLink(link string) (uint64, error) {
+	open := strings.IndexByte(link, '[')
+	if open < 0 || !strings.HasSuffix(link, "]") {
+		return 0, fmt.Errorf("malformed namespace link: %q", link)
+	}
+	return strconv.ParseUint(link[open+1:len(link)-1], 10, 64)
+}
+
+// GroupByNamespace groups the processes by their namespace of the given
+// kind, for example all processes sharing a network namespace. Processes
+// whose namespaces cannot be read are left out.
+func GroupByNamespace(kind NamespaceKind) (map[uint64][]*Process, error) {
+	procs, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[uint64][]*Process)
+	for _, p := range procs {
+		ino, err := p.namespace(kind)
+		if err != nil {
+			continue
+		}
+		ret[ino] = append(ret[ino], p)
+	}
+	return ret, nil
+}
+
+// NamespacePids returns the pid of the process in each nested pid
+// namespace it belongs to, from the namespace of /proc to the namespace
+// of the process itself.
+func (p Process) NamespacePids() ([]uint32, error) {
+	kv, err := linux.ReadKeyValues(linux.PidPath(p.Pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	// NSpid appeared in Linux 4.1.
+	s, ok := kv["NSpid"]
+	if !ok {
+		return []uint32{p.Pid}, nil
+	}
+
+	var ret []uint32
+	for _, f := range strings.Fields(s) {
+		n, err := strconv.ParseUint(f, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed NSpid: %q", s)
+		}
+		ret = append(ret, uint32(n))
+	}
+	return ret, nil
+}
+
+// NamespacePid returns the pid of the process as seen from inside its own
+// pid namespace, for example 1 for the init process of a container.
+func (p Process) NamespacePid() (uint32, error) {
+	pids, err := p.NamespacePids()
+	if err != nil {
+		return 0, err
+	}
+	return pids[len(pids)-1], nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/entuerto/sysmon"
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// NodeMemory is the memory of a process resident on a NUMA node.
+type NodeMemory struct {
+	Node int         `json:"node"`
+	Size sysmon.Size `json:"size"`
+	Anon sysmon.Size `json:"anon"` // in mappings without a backing file, such as the heap and stacks
+	Huge sysmon.Size `json:"huge"` // in hugetlbfs mappings
+}
+
+// NUMAMemory returns where the resident memory of the process lives, by
+// NUMA node in ascending order, from /proc/<pid>/numa_maps.
+func (p Process) NUMAMemory() ([]*NodeMemory, error) {
+	lines, err := linux.ReadLines(linux.PidPath(p.Pid, "numa_maps"))
+	if err != nil {
+		return nil, err
+	}
+	return parseNumaMaps(lines)
+}
+
+// parseNumaMaps parses lines of the form
+// "7f3c2a000000 default file=/usr/lib/libc.so.6 mapped=12 N0=8 N1=4 kernelpagesize_kB=4",
+// where N<node> counts the pages resident on each node.
+func parseNumaMaps(lines []string) ([]*NodeMemory, error) {
+	var ret []*NodeMemory
+	nodes := make(map[int]*NodeMemory)
+
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) < 2 {
+			continue
+		}
+
+		var (
+			anon     = true
+			huge     bool
+			pageSize = uint64(4096)
+			pages    = make(map[int]uint64)
+		)
+		for _, kv := range f[2:] {
+			i := strings.IndexByte(kv, '=')
+			if i < 0 {
+				if kv == "huge" {
+					huge = true
+				}
+				continue
+			}
+
+			key, value := kv[:i], kv[i+1:]
+			switch {
+			case key == "file":
+				anon = false
+			case key == "kernelpagesize_kB":
+				kb, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("malformed numa_maps: %q", l)
+				}
+				pageSize = kb * 1024
+			case len(key) > 1 && key[0] == 'N':
+				node, err := strconv.Atoi(key[1:])
+				if err != nil {
+					continue
+				}
+				n, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("malformed numa_maps: %q", l)
+				}
+				pages[node] += n
+			}
+		}
+
+		for node, n := range pages {
+			nm, ok := nodes[node]
+			if !ok {
+				nm = &NodeMemory{Node: node}
+				nodes[node] = nm
+				ret = append(ret, nm)
+			}
+
+			size := sysmon.Size(n * pageSize)
+			nm.Size += size
+			switch {
+			case huge:
+				nm.Huge += size
+			case anon:
+				nm.Anon += size
+			}
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Node < ret[j].Node })
+	return ret, nil
+}
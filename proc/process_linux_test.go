@@ -0,0 +1,755 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+	"github.com/entuerto/sysmon/internal/linux/linuxtest"
+)
+
+func startChild(t *testing.T, name string, args ...string) *Process {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	p, err := OpenProcess(uint32(cmd.Process.Pid))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return p
+}
+
+func TestWaitExitCode(t *testing.T) {
+	p := startChild(t, "sh", "-c", "exit 3")
+	defer p.Release()
+
+	es, err := p.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if es.Code != 3 {
+		t.Errorf("exit code = %d, want 3", es.Code)
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if _, err := p.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitWithoutPidfd(t *testing.T) {
+	p := startChild(t, "sh", "-c", "exit 3")
+	defer p.Release()
+
+	// The child stays a zombie until it is reaped.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		exited, err := p.exited(-1)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if exited {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("zombie not seen as exited")
+		}
+	}
+
+	es, err := p.wait4(&ExitStatus{Pid: p.Pid, Code: -1})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if es.Code != 3 {
+		t.Errorf("exit code = %d, want 3", es.Code)
+	}
+	if _, err := os.Stat(fmt.Sprintf("/proc/%d", p.Pid)); !os.IsNotExist(err) {
+		t.Errorf("child not reaped: %v", err)
+	}
+}
+
+func TestWaitReusedPidWithoutPidfd(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	reused := *p
+	reused.startTime++
+	if exited, err := reused.exited(-1); err != nil || !exited {
+		t.Errorf("exited = %v, %v for a pid with another start time", exited, err)
+	}
+}
+
+func TestSignalReusedPid(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	// Without a pidfd held, the start time tells a process that reused the
+	// pid apart.
+	same := *p
+	same.handle = 0
+	if err := same.Signal(syscall.Signal(0)); err != nil {
+		t.Errorf("error: %v", err)
+	}
+
+	reused := same
+	reused.startTime++
+	if err := reused.Signal(syscall.SIGTERM); err == nil {
+		t.Error("signalled a process with another start time")
+	}
+}
+
+func TestSuspendResume(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	if err := p.Suspend(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	s, err := newProcess(p.Pid)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if s.Status != StatusStopped {
+		t.Errorf("status = %q, want %q", s.Status, StatusStopped)
+	}
+
+	if err := p.Resume(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	// The shell ignores SIGTERM, so Terminate has to escalate to SIGKILL.
+	p := startChild(t, "sh", "-c", "trap '' TERM; sleep 10")
+	defer p.Release()
+
+	time.Sleep(100 * time.Millisecond)
+
+	es, err := p.Terminate(200 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if es.Signal != syscall.SIGKILL {
+		t.Errorf("signal = %v, want %v", es.Signal, syscall.SIGKILL)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	lines := []string{
+		"Limit                     Soft Limit           Hard Limit           Units     ",
+		"Max cpu time              unlimited            unlimited            seconds   ",
+		"Max open files            1024                 1048576              files     ",
+		"Max nice priority         0                    0                    ",
+	}
+
+	limits, err := parseLimits(lines)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	want := []Rlimit{
+		{RlimitCPU, RlimInfinity, RlimInfinity, "seconds"},
+		{RlimitNoFile, 1024, 1048576, "files"},
+		{RlimitNice, 0, 0, ""},
+	}
+	if len(limits) != len(want) {
+		t.Fatalf("got %d limits, want %d", len(limits), len(want))
+	}
+	for i, rl := range limits {
+		if *rl != want[i] {
+			t.Errorf("limit %d = %#v, want %#v", i, *rl, want[i])
+		}
+	}
+}
+
+func TestSetRlimit(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	if err := p.SetRlimit(RlimitNoFile, 64, 128); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	usage, err := p.RlimitUsage()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	for _, ru := range usage {
+		if ru.Resource != RlimitNoFile {
+			continue
+		}
+		if ru.Soft != 64 || ru.Hard != 128 {
+			t.Errorf("NOFILE = %d/%d, want 64/128", ru.Soft, ru.Hard)
+		}
+		if ru.Used == 0 {
+			t.Error("no open files counted")
+		}
+		return
+	}
+	t.Error("no NOFILE usage")
+}
+
+func TestSetRlimitReusedPid(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	reused := *p
+	reused.startTime++
+	if err := reused.SetRlimit(RlimitNoFile, 64, 128); err == nil {
+		t.Error("changed a limit of a process with another start time")
+	}
+
+	limits, err := p.Rlimits()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	for _, rl := range limits {
+		if rl.Resource == RlimitNoFile && rl.Soft == 64 && rl.Hard == 128 {
+			t.Error("limit changed through a reused pid")
+		}
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	if ino, err := parseNamespaceLink("net:[4026531992]"); err != nil || ino != 4026531992 {
+		t.Errorf("parseNamespaceLink = %d, %v", ino, err)
+	}
+
+	p := Process{Pid: uint32(os.Getpid())}
+
+	ns, err := p.Namespaces()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	groups, err := GroupByNamespace(NamespaceNet)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	found := false
+	for _, q := range groups[ns[NamespaceNet]] {
+		found = found || q.Pid == p.Pid
+	}
+	if !found {
+		t.Errorf("process %d not in its network namespace group", p.Pid)
+	}
+
+	pid, err := p.NamespacePid()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if pid == 0 {
+		t.Error("no pid in namespace")
+	}
+}
+
+func TestNamespacesMissingKinds(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{"proc/42/stat": ""})()
+
+	// No cgroup namespace before Linux 4.6.
+	dir := linux.PidPath(42, "ns")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{"ipc", "mnt", "net"} {
+		if err := os.Symlink(kind + ":[4026531992]", filepath.Join(dir, kind)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ns, err := Process{Pid: 42}.Namespaces()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(ns) != 3 || ns[NamespaceNet] != 4026531992 {
+		t.Errorf("namespaces = %v", ns)
+	}
+
+	if _, err := (Process{Pid: 43}).Namespaces(); !os.IsNotExist(err) {
+		t.Errorf("error = %v for a process that is gone", err)
+	}
+}
+
+func TestScheduling(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	if err := p.SetNice(5); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if nice, err := p.Nice(); err != nil || nice != 5 {
+		t.Errorf("nice = %d, %v, want 5", nice, err)
+	}
+
+	if policy, prio, err := p.SchedPolicy(); err != nil || policy != SchedOther || prio != 0 {
+		t.Errorf("policy = %v/%d, %v, want OTHER/0", policy, prio, err)
+	}
+
+	cpus, err := p.CPUAffinity()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := p.SetCPUAffinity(cpus[:1]); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	threads, err := p.Threads()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	for _, th := range threads {
+		got, err := th.CPUAffinity()
+		if err != nil || len(got) != 1 || got[0] != cpus[0] {
+			t.Errorf("thread %d affinity = %v, %v, want [%d]", th.ThreadID, got, err, cpus[0])
+		}
+	}
+}
+
+func TestSchedulingReusedPid(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	before, err := p.Nice()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	reused := *p
+	reused.startTime++
+	if err := reused.SetNice(before + 1); err == nil {
+		t.Error("changed the nice value of a process with another start time")
+	}
+	if nice, err := p.Nice(); err != nil || nice != before {
+		t.Errorf("nice = %d, %v, want %d", nice, err, before)
+	}
+
+	cpus, err := p.CPUAffinity()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := reused.SetCPUAffinity(cpus[:1]); err == nil {
+		t.Error("changed the affinity of a process with another start time")
+	}
+}
+
+func TestSchedStats(t *testing.T) {
+	run, delay, slices, err := parseSchedStat("2217463 30110 12")
+	if err != nil || run != 2217463 || delay != 30110 || slices != 12 {
+		t.Errorf("parseSchedStat = %v, %v, %d, %v", run, delay, slices, err)
+	}
+
+	p := Process{Pid: uint32(os.Getpid())}
+
+	ss, err := p.SchedStats()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if ss.VoluntaryCtxtSwitches + ss.NonvoluntaryCtxtSwitches == 0 {
+		t.Error("no context switches")
+	}
+
+	threads, err := p.Threads()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if _, err := threads[0].SchedStats(); err != nil {
+		t.Errorf("error: %v", err)
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "userdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passwd := filepath.Join(dir, "passwd")
+	group := filepath.Join(dir, "group")
+	ioutil.WriteFile(passwd, []byte("root:x:0:0:root:/root:/bin/sh\nwww-data:x:33:33::/var/www:/bin/false\n"), 0644)
+	ioutil.WriteFile(group, []byte("root:x:0:\nwww-data:x:33:\nadm:x:4:syslog\n"), 0644)
+
+	saved := users
+	users = &userDB{passwdFile: passwd, groupFile: group}
+	defer func() { users = saved }()
+
+	kv := map[string]string{
+		"Uid"    : "33\t33\t0\t33",
+		"Gid"    : "33\t33\t33\t33",
+		"Groups" : "4 33 1000",
+	}
+
+	c, err := parseCredentials(kv)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if c.UID.Saved != 0 || c.UserNames.Saved != "root" || c.UserNames.Effective != "www-data" {
+		t.Errorf("users = %+v %+v", c.UID, c.UserNames)
+	}
+	if strings.Join(c.GroupList, ",") != "adm,www-data,1000" {
+		t.Errorf("groups = %v", c.GroupList)
+	}
+}
+
+func TestSecurity(t *testing.T) {
+	kv := map[string]string{
+		"CapInh"     : "0000000000000000",
+		"CapPrm"     : "0000000000200000",
+		"CapEff"     : "0000000000200000",
+		"CapBnd"     : "000001ffffffffff",
+		"CapAmb"     : "0000000000000000",
+		"NoNewPrivs" : "1",
+		"Seccomp"    : "2",
+	}
+
+	s, err := parseSecurity(kv)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if !s.Effective.Has(CapSysAdmin) || s.Effective.String() != "CAP_SYS_ADMIN" {
+		t.Errorf("effective = %s", s.Effective)
+	}
+	if len(s.Bounding.Capabilities()) != 41 {
+		t.Errorf("bounding = %s", s.Bounding)
+	}
+	if s.Seccomp != SeccompFilter || !s.NoNewPrivs {
+		t.Errorf("seccomp = %v, noNewPrivs = %t", s.Seccomp, s.NoNewPrivs)
+	}
+
+	p := Process{Pid: uint32(os.Getpid())}
+	if _, err := p.Security(); err != nil {
+		t.Errorf("error: %v", err)
+	}
+}
+
+func TestStartTime(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	u, err := p.Usage()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if d := time.Since(u.CreationTime); d < -time.Second || d > 5*time.Second {
+		t.Errorf("creation time = %v, %v ago", u.CreationTime, d)
+	}
+
+	age, err := p.Age()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if age < 0 || age > 5*time.Second {
+		t.Errorf("age = %v", age)
+	}
+
+	// A process with the same pid but another start time must not be signalled.
+	key := p.Key()
+	reused := Process{Pid: key.Pid, startTime: key.StartTime + 1}
+	if err := reused.Signal(syscall.SIGTERM); err == nil {
+		t.Error("signalled a process with another start time")
+	}
+}
+
+func TestParseSmaps(t *testing.T) {
+	lines := []string{
+		"55d0c6a00000-55d0c6a02000 r--p 00000000 fd:01 1835054                    /usr/bin/cat",
+		"Size:                  8 kB",
+		"Rss:                   8 kB",
+		"Pss:                   4 kB",
+		"Shared_Clean:          8 kB",
+		"VmFlags: rd mr mw me sd",
+		"55d0c6a02000-55d0c6a07000 r-xp 00002000 fd:01 1835054                    /usr/bin/cat",
+		"Size:                 20 kB",
+		"Rss:                  20 kB",
+		"Pss:                  10 kB",
+		"7f5e3c000000-7f5e3c021000 rw-p 00000000 00:00 0 ",
+		"Size:                132 kB",
+		"Rss:                   4 kB",
+		"Private_Dirty:         4 kB",
+		"AnonHugePages:         0 kB",
+		"7f5e3c100000-7f5e3c121000 rw-p 00000000 00:00 0 ",
+		"Size:                132 kB",
+		"Rss:                   8 kB",
+	}
+
+	maps, err := parseSmaps(lines)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(maps) != 4 {
+		t.Fatalf("got %d maps, want 4", len(maps))
+	}
+	if m := maps[1]; m.Perms != "r-xp" || m.Offset != 0x2000 || m.Device != "fd:01" || m.Inode != 1835054 || m.Path != "/usr/bin/cat" || m.Rss != 20*1024 {
+		t.Errorf("map = %#v", m)
+	}
+	if m := maps[2]; m.Path != "" || m.PrivateDirty != 4*1024 {
+		t.Errorf("map = %#v", m)
+	}
+
+	// Anonymous mappings are not merged.
+	grouped := groupMemoryMaps(maps)
+	if len(grouped) != 3 {
+		t.Fatalf("got %d groups, want 3", len(grouped))
+	}
+	if g := grouped[0]; g.Start != 0x55d0c6a00000 || g.End != 0x55d0c6a07000 || g.Rss != 28*1024 || g.Pss != 14*1024 {
+		t.Errorf("group = %#v", g)
+	}
+	if g := grouped[2]; g.Start != 0x7f5e3c100000 || g.Perms != "rw-p" || g.Rss != 8*1024 {
+		t.Errorf("group = %#v", g)
+	}
+}
+
+func TestParseNumaMaps(t *testing.T) {
+	lines := []string{
+		"55f86732f000 default file=/usr/bin/head mapped=2 N0=2 kernelpagesize_kB=4",
+		"55f86733a000 default heap anon=3 dirty=3 N0=1 N1=2 kernelpagesize_kB=4",
+		"7f3c00000000 bind:1 file=/dev/hugepages/buf huge dirty=1 N1=1 kernelpagesize_kB=2048",
+		"7ffd1b3bd000 default stack anon=2 dirty=2 N1=2 kernelpagesize_kB=4",
+	}
+
+	nodes, err := parseNumaMaps(lines)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("got %d nodes, want 2", len(nodes))
+	}
+	if n := nodes[0]; n.Node != 0 || n.Size != 12*1024 || n.Anon != 4*1024 || n.Huge != 0 {
+		t.Errorf("node = %#v", n)
+	}
+	if n := nodes[1]; n.Node != 1 || n.Size != 2*1024*1024 + 16*1024 || n.Anon != 16*1024 || n.Huge != 2*1024*1024 {
+		t.Errorf("node = %#v", n)
+	}
+
+	if _, err := parseNumaMaps([]string{"55f86732f000 default N0=x"}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestNUMAMemory(t *testing.T) {
+	p := Process{Pid: uint32(os.Getpid())}
+
+	nodes, err := p.NUMAMemory()
+	if err != nil {
+		t.Skipf("no numa_maps: %v", err)
+	}
+	if len(nodes) == 0 || nodes[0].Size == 0 {
+		t.Errorf("got %v", nodes)
+	}
+}
+
+func TestHugePages(t *testing.T) {
+	p := Process{Pid: uint32(os.Getpid())}
+
+	hu, err := p.HugePages()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if hu.HugetlbPages != 0 {
+		t.Errorf("got %#v, the test does not map huge pages", hu)
+	}
+}
+
+func TestParseSyscall(t *testing.T) {
+	si, err := parseSyscall("0 0x3 0x7fd7370d4000 0x20000 0x7fd737107b60 0xffffffff 0x0 0x7ffd1b3dceb8 0x7fd7371f029d")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if si.Number != 0 || si.Args[0] != 3 || si.Args[2] != 0x20000 || si.SP != 0x7ffd1b3dceb8 || si.PC != 0x7fd7371f029d {
+		t.Errorf("syscall = %#v", si)
+	}
+	if si.Name != syscallNames[0] {
+		t.Errorf("got name %q, want %q", si.Name, syscallNames[0])
+	}
+
+	si, err = parseSyscall("-1 0x7ffd1b3dceb8 0x7fd7371f029d")
+	if err != nil || si.Number != -1 || si.String() != "not in a system call" {
+		t.Errorf("syscall = %#v, %v", si, err)
+	}
+
+	si, err = parseSyscall("running")
+	if err != nil || !si.Running {
+		t.Errorf("syscall = %#v, %v", si, err)
+	}
+
+	if _, err := parseSyscall("0 0x3"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestParseKernelStack(t *testing.T) {
+	stack := parseKernelStack([]string{
+		"[<0>] do_nanosleep+0x6c/0x190",
+		"[<0>] hrtimer_nanosleep+0xbb/0x180",
+		"[<ffffffff8a2d1c2e>] do_syscall_64+0x70/0x1e0",
+	})
+
+	want := []string{"do_nanosleep+0x6c/0x190", "hrtimer_nanosleep+0xbb/0x180", "do_syscall_64+0x70/0x1e0"}
+	if strings.Join(stack, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", stack, want)
+	}
+}
+
+func TestThreadDiagnostics(t *testing.T) {
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	time.Sleep(100 * time.Millisecond)
+
+	threads, err := p.Threads()
+	if err != nil || len(threads) == 0 {
+		t.Fatalf("threads = %v, %v", threads, err)
+	}
+
+	wchan, err := threads[0].WaitChannel()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if wchan == "" {
+		t.Error("sleeping thread has no wait channel")
+	}
+
+	si, err := threads[0].Syscall()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if si.Running || si.Number < 0 {
+		t.Errorf("sleeping thread not in a system call: %#v", si)
+	}
+	t.Logf("%s in %s", si, wchan)
+}
+
+func TestAnomalies(t *testing.T) {
+	at := &anomalyTracker{}
+	start := time.Now()
+
+	// Pids above the kernel maximum, so no /proc entry is ever read.
+	procs := []*Process{
+		{Pid: 1, Name: "init", Status: StatusSleeping},
+		{Pid: 5000000, ParentId: 1, Name: "runner", Status: StatusSleeping},
+		{Pid: 5000001, ParentId: 5000000, Name: "job", Status: StatusZombie},
+		{Pid: 5000002, ParentId: 5000000, Name: "job", Status: StatusZombie},
+		{Pid: 5000003, ParentId: 5000000, Name: "worker", Status: StatusSleeping},
+		{Pid: 5000004, ParentId: 1, Name: "backup", Status: StatusDiskSleep},
+	}
+
+	rep := at.report(procs, start)
+	if len(rep.Zombies) != 1 || rep.Zombies[0].Parent.Pid != 5000000 || len(rep.Zombies[0].Zombies) != 2 {
+		t.Fatalf("zombies = %#v", rep.Zombies)
+	}
+	if len(rep.Orphans) != 0 || len(rep.DiskSleep) != 0 {
+		t.Fatalf("report = %#v", rep)
+	}
+
+	// The runner exits, its worker is adopted by init.
+	procs = append(procs[:1], procs[3:]...)
+	procs[2].ParentId = 1
+
+	rep = at.report(procs, start.Add(LongDiskSleep))
+	if len(rep.Zombies) != 1 || rep.Zombies[0].Parent != nil {
+		t.Fatalf("zombies = %#v", rep.Zombies)
+	}
+	if z := rep.Zombies[0].Zombies[0]; z.Process.Pid != 5000002 || z.Duration != LongDiskSleep {
+		t.Errorf("zombie = %#v", z)
+	}
+	if len(rep.Orphans) != 1 || rep.Orphans[0].Process.Pid != 5000003 || rep.Orphans[0].Duration != 0 {
+		t.Errorf("orphans = %#v", rep.Orphans)
+	}
+	if len(rep.DiskSleep) != 1 || rep.DiskSleep[0].Process.Pid != 5000004 {
+		t.Errorf("disk sleep = %#v", rep.DiskSleep)
+	}
+
+	// Still adopted, the orphan is reported since it was first seen.
+	rep = at.report(procs, start.Add(LongDiskSleep + time.Second))
+	if len(rep.Orphans) != 1 || rep.Orphans[0].Process.Pid != 5000003 || rep.Orphans[0].Duration != time.Second {
+		t.Errorf("orphans = %#v", rep.Orphans)
+	}
+
+	// Back to sleep, the tracker forgets about it.
+	procs[3].Status = StatusSleeping
+	at.report(procs, start.Add(2 * LongDiskSleep))
+	procs[3].Status = StatusDiskSleep
+	rep = at.report(procs, start.Add(3 * LongDiskSleep))
+	if len(rep.DiskSleep) != 0 {
+		t.Errorf("disk sleep = %#v", rep.DiskSleep)
+	}
+}
+
+func TestWatchPolling(t *testing.T) {
+	defer func(use bool) { UseProcConnector = use }(UseProcConnector)
+	UseProcConnector = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := Watch(ctx, 50*time.Millisecond)
+
+	// Let the first scan happen before the child starts.
+	time.Sleep(100 * time.Millisecond)
+	p := startChild(t, "sleep", "10")
+	defer p.Release()
+	defer p.Kill()
+
+	for ev := range events {
+		if ev.Type == ProcessStarted && ev.Key.Pid == p.Pid {
+			return
+		}
+	}
+	t.Errorf("no start event for %d", p.Pid)
+}
+
+func TestProbeConnector(t *testing.T) {
+	defer func(d time.Duration) { connectorProbeTimeout = d }(connectorProbeTimeout)
+	connectorProbeTimeout = 100 * time.Millisecond
+
+	// A descriptor that never becomes readable, like a connector joined
+	// outside the initial network namespace.
+	var fds [2]int
+	if err := syscall.Pipe(fds[:]); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	if err := probeConnector(fds[0], make([]byte, 4096)); err != errNoConnectorEvents {
+		t.Errorf("error = %v, want %v", err, errNoConnectorEvents)
+	}
+}
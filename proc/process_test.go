@@ -6,101 +6,127 @@
 package proc
 
 import (
-	"fmt"
+	"context"
 	"testing"
+	"time"
+
+	"github.com/entuerto/sysmon"
 )
 
-const PID = 10336
+func TestDiffProcesses(t *testing.T) {
+	a := &Process{Pid: 1, Executable: "/sbin/init", startTime: 1}
+	b := &Process{Pid: 2, Executable: "/bin/sh", startTime: 5}
+	c := &Process{Pid: 2, Executable: "/bin/sh", startTime: 9}  // pid 2 reused
+	d := &Process{Pid: 1, Executable: "/lib/systemd/systemd", startTime: 1}
 
-func TestFindProcess(t *testing.T) {
-	p, err := OpenProcess(PID)
-	if err != nil {
-		t.Errorf("error: %v", err)
-	}
+	prev := map[ProcessKey]*Process{a.Key(): a, b.Key(): b}
+	cur := map[ProcessKey]*Process{d.Key(): d, c.Key(): c}
 
-	fmt.Printf("%#v\n", p)
-}
-
-func TestUsage(t *testing.T) {
-	p, err := OpenProcess(PID)
-	if err != nil {
-		t.Errorf("error: %v", err)
+	got := make(map[EventType]ProcessKey)
+	for _, ev := range diffProcesses(prev, cur, time.Now()) {
+		got[ev.Type] = ev.Key
 	}
 
-	u, err := p.Usage()
-	if err != nil {
-		t.Errorf("error: %v", err)
+	want := map[EventType]ProcessKey{
+		ProcessExited  : b.Key(),
+		ProcessStarted : c.Key(),
+		ProcessExec    : a.Key(),
 	}
-
-	fmt.Printf("%#v\n", u)
-}
-
-func TestIOCounters(t *testing.T) {
-	p, err := OpenProcess(PID)
-	if err != nil {
-		t.Errorf("error: %v", err)
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
 	}
-
-	ioc, err := p.IOCounters()
-	if err != nil {
-		t.Errorf("error: %v", err)
+	for typ, key := range want {
+		if got[typ] != key {
+			t.Errorf("%s event for %s, want %s", typ, got[typ], key)
+		}
 	}
-
-	fmt.Printf("%#v\n", ioc)
 }
 
-func TestMemoryInfo(t *testing.T) {
-	p, err := OpenProcess(PID)
+func TestTop(t *testing.T) {
+	top, err := Top(context.Background(), SortByRSS, 5, 100*time.Millisecond)
 	if err != nil {
-		t.Errorf("error: %v", err)
+		t.Fatalf("error: %v", err)
 	}
 
-	pmc, err := p.MemoryInfo()
-	if err != nil {
-		t.Errorf("error: %v", err)
-	}
+	defer func() {
+		for _, ps := range top {
+			ps.Release()
+		}
+	}()
 
-	fmt.Printf("%#v\n", pmc)
+	if len(top) == 0 || len(top) > 5 {
+		t.Fatalf("got %d processes", len(top))
+	}
+	for i := 1; i < len(top); i++ {
+		if top[i].RSS > top[i-1].RSS {
+			t.Errorf("not sorted: %s after %s", top[i].RSS, top[i-1].RSS)
+		}
+	}
 }
 
-func TestModules(t *testing.T) {
-	p, err := OpenProcess(PID)
+func TestParseSelector(t *testing.T) {
+	sel, err := ParseSelector("user=www-data name~^php rss>200M cpu>2.5 ppid=1 state=sleeping")
 	if err != nil {
-		t.Errorf("error: %v", err)
+		t.Fatalf("error: %v", err)
 	}
 
-	modules, err := p.Modules()
-	if err != nil {
-		t.Errorf("error: %v", err)
+	want := "name~^php user=www-data ppid=1 state=sleeping rss>209715200 cpu>2.5"
+	if sel.String() != want {
+		t.Errorf("got %q, want %q", sel.String(), want)
+	}
+	if again, err := ParseSelector(want); err != nil || again.String() != want {
+		t.Errorf("round trip = %v, %v", again, err)
 	}
 
-	if len(modules) == 0 {
-		t.Errorf("error: No modules!")
+	ps := &ProcessStat{
+		Process: &Process{
+			Pid      : 42,
+			ParentId : 1,
+			Name     : "php-fpm",
+			UserName : "www-data",
+			Status   : "sleeping",
+		},
+		RSS        : 300 * sysmon.MB,
+		CPUPercent : 3,
 	}
-/*
-	for _, m := range modules {
-		fmt.Printf("%#v\n", m)
+	if !sel.Match(ps) {
+		t.Errorf("%s does not match %#v", sel, *ps)
 	}
-*/
-}
 
-func TestThreads(t *testing.T) {
-	p, err := OpenProcess(PID)
-	if err != nil {
-		t.Errorf("error: %v", err)
+	ps.RSS = 100 * sysmon.MB
+	if sel.Match(ps) {
+		t.Errorf("%s matches %#v", sel, *ps)
 	}
 
-	threads, err := p.Threads()
-	if err != nil {
-		t.Errorf("error: %v", err)
+	sel, _ = ParseSelector("name=php* cmd=--pool")
+	ps.CmdLine = "php-fpm --pool www"
+	if !sel.Match(ps) {
+		t.Errorf("%s does not match %#v", sel, *ps)
+	}
+
+	for _, s := range []string{"name", "rss>lots", "color=red", "name~(", "ppid=", "pid>1", "cpu>=2.5"} {
+		if _, err := ParseSelector(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
 	}
+}
 
-	if len(threads) == 0 {
-		t.Errorf("error: No threads!")
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		size sysmon.Size
+	}{
+		{"4096", 4096},
+		{"512K", 512 * sysmon.KB},
+		{"200M", 200 * sysmon.MB},
+		{"200MB", 200 * sysmon.MB},
+		{"1.5GiB", sysmon.GB + 512 * sysmon.MB},
 	}
-/*
-	for _, t := range threads {
-		fmt.Printf("%#v\n", t)
+
+	for _, tt := range tests {
+		size, err := parseSize(tt.s)
+		if err != nil || size != tt.size {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", tt.s, size, err, tt.size)
+		}
 	}
-*/
 }
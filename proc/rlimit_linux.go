@@ -0,0 +1,273 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// Resource identifies a process resource limit, see getrlimit(2).
+type Resource int
+
+const (
+	RlimitCPU Resource = iota
+	RlimitFSize
+	RlimitData
+	RlimitStack
+	RlimitCore
+	RlimitRSS
+	RlimitNProc
+	RlimitNoFile
+	RlimitMemLock
+	RlimitAS
+	RlimitLocks
+	RlimitSigPending
+	RlimitMsgQueue
+	RlimitNice
+	RlimitRtPrio
+	RlimitRtTime
+)
+
+// RlimInfinity is the value of a limit that is not enforced.
+const RlimInfinity = ^uint64(0)
+
+var resourceNames = []string{
+	"CPU", "FSIZE", "DATA", "STACK", "CORE", "RSS", "NPROC", "NOFILE",
+	"MEMLOCK", "AS", "LOCKS", "SIGPENDING", "MSGQUEUE", "NICE", "RTPRIO", "RTTIME",
+}
+
+// Names used by /proc/<pid>/limits, in Resource order.
+var resourceDescriptions = []string{
+	"Max cpu time", "Max file size", "Max data size", "Max stack size",
+	"Max core file size", "Max resident set", "Max processes", "Max open files",
+	"Max locked memory", "Max address space", "Max file locks", "Max pending signals",
+	"Max msgqueue size", "Max nice priority", "Max realtime priority", "Max realtime timeout",
+}
+
+func (r Resource) String() string {
+	if r >= 0 && int(r) < len(resourceNames) {
+		return resourceNames[r]
+	}
+	return fmt.Sprintf("Resource(%d)", int(r))
+}
+
+//---------------------------------------------------------------------------------------
+
+type Rlimit struct {
+	Resource Resource `json:"resource"`
+	Soft     uint64   `json:"soft"` // RlimInfinity when unlimited
+	Hard     uint64   `json:"hard"` // RlimInfinity when unlimited
+	Unit     string   `json:"unit"`
+}
+
+func (rl Rlimit) GoString() string {
+	s := []string{"Rlimit{",
+			fmt.Sprintf("  Resource : %s", rl.Resource),
+			fmt.Sprintf("  Soft     : %s", formatLimit(rl.Soft)),
+			fmt.Sprintf("  Hard     : %s", formatLimit(rl.Hard)),
+			fmt.Sprintf("  Unit     : %s", rl.Unit),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+func formatLimit(v uint64) string {
+	if v == RlimInfinity {
+		return "unlimited"
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// Rlimits returns the soft and hard value of every resource limit of the
+// process.
+func (p Process) Rlimits() ([]*Rlimit, error) {
+	lines, err := linux.ReadLines(linux.PidPath(p.Pid, "limits"))
+	if err != nil {
+		return nil, err
+	}
+	return parseLimits(lines)
+}
+
+func parseLimits(lines []string) ([]*Rlimit, error) {
+	var ret []*Rlimit
+
+	for _, l := range lines {
+		for r, desc := range resourceDescriptions {
+			if !strings.HasPrefix(l, desc + " ") {
+				continue
+			}
+
+			// soft hard [unit]
+			f := strings.Fields(l[len(desc):])
+			if len(f) < 2 {
+				return nil, fmt.Errorf("malformed limit: %q", l)
+			}
+
+			soft, err := parseLimit(f[0])
+			if err != nil {
+				return nil, err
+			}
+			hard, err := parseLimit(f[1])
+			if err != nil {
+				return nil, err
+			}
+
+			rl := &Rlimit{
+				Resource : Resource(r),
+				Soft     : soft,
+				Hard     : hard,
+			}
+			if len(f) > 2 {
+				rl.Unit = f[2]
+			}
+			ret = append(ret, rl)
+			break
+		}
+	}
+	return ret, nil
+}
+
+func parseLimit(s string) (uint64, error) {
+	if s == "unlimited" {
+		return RlimInfinity, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// SetRlimit changes a resource limit of the process with prlimit(2). Raising
+// the hard limit requires CAP_SYS_RESOURCE. It fails with ESRCH when the pid
+// was reused by another process.
+func (p Process) SetRlimit(resource Resource, soft, hard uint64) error {
+	if err := p.checkStartTime("prlimit64"); err != nil {
+		return err
+	}
+
+	rl := syscall.Rlimit{
+		Cur : soft,
+		Max : hard,
+	}
+
+	_, _, e := syscall.Syscall6(syscall.SYS_PRLIMIT64, uintptr(p.Pid), uintptr(resource), uintptr(unsafe.Pointer(&rl)), 0, 0, 0)
+	if e != 0 {
+		return os.NewSyscallError("prlimit64", e)
+	}
+	return nil
+}
+
+//---------------------------------------------------------------------------------------
+
+type RlimitUsage struct {
+	Rlimit
+	Used uint64 `json:"used"` // current usage, in the unit of the limit
+}
+
+func (ru RlimitUsage) GoString() string {
+	s := []string{"RlimitUsage{",
+			fmt.Sprintf("  Resource : %s", ru.Resource),
+			fmt.Sprintf("  Soft     : %s", formatLimit(ru.Soft)),
+			fmt.Sprintf("  Hard     : %s", formatLimit(ru.Hard)),
+			fmt.Sprintf("  Used     : %d", ru.Used),
+			fmt.Sprintf("  Unit     : %s", ru.Unit),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// Percent returns the usage as a percentage of the soft limit, 0 when the
+// resource is unlimited.
+func (ru RlimitUsage) Percent() float64 {
+	if ru.Soft == RlimInfinity || ru.Soft == 0 {
+		return 0
+	}
+	return float64(ru.Used) / float64(ru.Soft) * 100
+}
+
+// RlimitUsage reports the current usage of the process against each limit
+// that can be measured: open files against NOFILE, the threads of the user
+// against NPROC, memory against AS, DATA, STACK, RSS and MEMLOCK, queued
+// signals against SIGPENDING and CPU seconds against CPU.
+func (p Process) RlimitUsage() ([]*RlimitUsage, error) {
+	limits, err := p.Rlimits()
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := linux.ReadKeyValues(linux.PidPath(p.Pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[Resource]uint64{
+		RlimitCPU     : uint64(linux.TicksToDuration(st.UTime + st.STime).Seconds()),
+		RlimitData    : linux.ParseKB(kv["VmData"]),
+		RlimitStack   : linux.ParseKB(kv["VmStk"]),
+		RlimitRSS     : linux.ParseKB(kv["VmRSS"]),
+		RlimitMemLock : linux.ParseKB(kv["VmLck"]),
+		RlimitAS      : linux.ParseKB(kv["VmSize"]),
+	}
+
+	// SigQ: queued/limit
+	if q := strings.SplitN(kv["SigQ"], "/", 2); len(q) == 2 {
+		used[RlimitSigPending], _ = strconv.ParseUint(q[0], 10, 64)
+	}
+
+	if n, err := linux.CountEntries(linux.PidPath(p.Pid, "fd")); err == nil {
+		used[RlimitNoFile] = uint64(n)
+	}
+
+	// NPROC limits the number of threads of the real user id.
+	if uid := strings.Fields(kv["Uid"]); len(uid) > 0 {
+		if n, err := userThreads(uid[0]); err == nil {
+			used[RlimitNProc] = n
+		}
+	}
+
+	var ret []*RlimitUsage
+	for _, rl := range limits {
+		n, ok := used[rl.Resource]
+		if !ok {
+			continue
+		}
+		ret = append(ret, &RlimitUsage{
+			Rlimit : *rl,
+			Used   : n,
+		})
+	}
+	return ret, nil
+}
+
+// userThreads counts the threads of all processes whose real user id is uid.
+func userThreads(uid string) (uint64, error) {
+	pids, err := linux.Pids()
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	for _, pid := range pids {
+		kv, err := linux.ReadKeyValues(linux.PidPath(pid, "status"))
+		if err != nil {
+			continue
+		}
+		if f := strings.Fields(kv["Uid"]); len(f) == 0 || f[0] != uid {
+			continue
+		}
+		t, _ := strconv.ParseUint(kv["Threads"], 10, 64)
+		n += t
+	}
+	return n, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// SchedPolicy is a Linux scheduling policy, see sched(7).
+type SchedPolicy int
+
+const (
+	SchedOther    SchedPolicy = 0
+	SchedFIFO     SchedPolicy = 1
+	SchedRR       SchedPolicy = 2
+	SchedBatch    SchedPolicy = 3
+	SchedIdle     SchedPolicy = 5
+	SchedDeadline SchedPolicy = 6
+)
+
+func (sp SchedPolicy) String() string {
+	switch sp {
+	case SchedOther:
+		return "OTHER"
+	case SchedFIFO:
+		return "FIFO"
+	case SchedRR:
+		return "RR"
+	case SchedBatch:
+		return "BATCH"
+	case SchedIdle:
+		return "IDLE"
+	case SchedDeadline:
+		return "DEADLINE"
+	}
+	return fmt.Sprintf("SchedPolicy(%d)", int(sp))
+}
+
+// Nice returns the nice value of the process, from -20 (highest priority)
+// to 19 (lowest).
+func (p Process) Nice() (int, error) {
+	return readNice(linux.PidPath(p.Pid, "stat"))
+}
+
+// SetNice changes the nice value of every thread of the process. Lowering
+// it requires CAP_SYS_NICE. It fails with ESRCH when the pid was reused by
+// another process.
+func (p Process) SetNice(nice int) error {
+	if err := p.checkStartTime("setpriority"); err != nil {
+		return err
+	}
+
+	err := p.eachThread(func(tid uint32) error {
+		return setNice(tid, nice)
+	})
+	return wrapSyscallError("setpriority", err)
+}
+
+// SchedPolicy returns the scheduling policy of the process and its real-time
+// priority, which is 0 for the non real-time policies.
+func (p Process) SchedPolicy() (SchedPolicy, int, error) {
+	return readSchedPolicy(linux.PidPath(p.Pid, "stat"))
+}
+
+// CPUAffinity returns the CPUs the process is allowed to run on.
+func (p Process) CPUAffinity() ([]int, error) {
+	return getAffinity(p.Pid)
+}
+
+// SetCPUAffinity restricts every thread of the process to the given CPUs.
+// It fails with ESRCH when the pid was reused by another process.
+func (p Process) SetCPUAffinity(cpus []int) error {
+	if err := p.checkStartTime("sched_setaffinity"); err != nil {
+		return err
+	}
+
+	err := p.eachThread(func(tid uint32) error {
+		return setAffinity(tid, cpus)
+	})
+	return wrapSyscallError("sched_setaffinity", err)
+}
+
+func (p Process) eachThread(fn func(tid uint32) error) error {
+	tids, err := linux.Tids(p.Pid)
+	if err != nil {
+		return err
+	}
+
+	for _, tid := range tids {
+		err := fn(tid)
+		// The thread exited since the list was read.
+		if err == syscall.ESRCH {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//---------------------------------------------------------------------------------------
+
+// Nice returns the nice value of the thread.
+func (t Thread) Nice() (int, error) {
+	return readNice(linux.TidPath(t.OwnerProcessID, t.ThreadID, "stat"))
+}
+
+// SetNice changes the nice value of the thread.
+func (t Thread) SetNice(nice int) error {
+	return wrapSyscallError("setpriority", setNice(t.ThreadID, nice))
+}
+
+// SchedPolicy returns the scheduling policy and real-time priority of the thread.
+func (t Thread) SchedPolicy() (SchedPolicy, int, error) {
+	return readSchedPolicy(linux.TidPath(t.OwnerProcessID, t.ThreadID, "stat"))
+}
+
+// CPUAffinity returns the CPUs the thread is allowed to run on.
+func (t Thread) CPUAffinity() ([]int, error) {
+	return getAffinity(t.ThreadID)
+}
+
+// SetCPUAffinity restricts the thread to the given CPUs.
+func (t Thread) SetCPUAffinity(cpus []int) error {
+	return wrapSyscallError("sched_setaffinity", setAffinity(t.ThreadID, cpus))
+}
+
+//---------------------------------------------------------------------------------------
+
+func readNice(path string) (int, error) {
+	st, err := linux.ReadStat(path)
+	if err != nil {
+		return 0, err
+	}
+	return int(st.Nice), nil
+}
+
+func readSchedPolicy(path string) (SchedPolicy, int, error) {
+	st, err := linux.ReadStat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return SchedPolicy(st.Policy), int(st.RtPriority), nil
+}
+
+func setNice(tid uint32, nice int) error {
+	// The thread id is a valid PRIO_PROCESS target and only affects that thread.
+	return syscall.Setpriority(syscall.PRIO_PROCESS, int(tid), nice)
+}
+
+// cpuMask is a cpu_set_t large enough for 1024 CPUs.
+type cpuMask [16]uint64
+
+const cpuMaskBits = int(unsafe.Sizeof(cpuMask{})) * 8
+
+func getAffinity(tid uint32) ([]int, error) {
+	var mask cpuMask
+
+	_, _, e := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, uintptr(tid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if e != 0 {
+		return nil, os.NewSyscallError("sched_getaffinity", e)
+	}
+
+	var cpus []int
+	for cpu := 0; cpu < cpuMaskBits; cpu++ {
+		if mask[cpu/64] & (1 << uint(cpu%64)) != 0 {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+func setAffinity(tid uint32, cpus []int) error {
+	var mask cpuMask
+
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= cpuMaskBits {
+			return fmt.Errorf("invalid cpu %d", cpu)
+		}
+		mask[cpu/64] |= 1 << uint(cpu%64)
+	}
+
+	_, _, e := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(tid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+func wrapSyscallError(name string, err error) error {
+	if _, ok := err.(syscall.Errno); ok {
+		return os.NewSyscallError(name, err)
+	}
+	return err
+}
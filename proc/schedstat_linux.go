@@ -0,0 +1,141 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+type SchedStats struct {
+	VoluntaryCtxtSwitches    uint64        `json:"voluntaryCtxtSwitches"`    // The task gave up the CPU, usually to wait for a resource.
+	NonvoluntaryCtxtSwitches uint64        `json:"nonvoluntaryCtxtSwitches"` // The task was preempted.
+	MinorFaults              uint64        `json:"minorFaults"`              // Page faults served without disk I/O.
+	MajorFaults              uint64        `json:"majorFaults"`              // Page faults that needed disk I/O.
+	RunTime                  time.Duration `json:"runTime"`                  // Time spent on a CPU.
+	RunDelay                 time.Duration `json:"runDelay"`                 // Time spent runnable, waiting on a run queue.
+	Timeslices               uint64        `json:"timeslices"`               // Number of timeslices run on a CPU.
+}
+
+func (ss SchedStats) GoString() string {
+	s := []string{"SchedStats{",
+			fmt.Sprintf("  VoluntaryCtxtSwitches    : %d", ss.VoluntaryCtxtSwitches),
+			fmt.Sprintf("  NonvoluntaryCtxtSwitches : %d", ss.NonvoluntaryCtxtSwitches),
+			fmt.Sprintf("  MinorFaults              : %d", ss.MinorFaults),
+			fmt.Sprintf("  MajorFaults              : %d", ss.MajorFaults),
+			fmt.Sprintf("  RunTime                  : %s", ss.RunTime),
+			fmt.Sprintf("  RunDelay                 : %s", ss.RunDelay),
+			fmt.Sprintf("  Timeslices               : %d", ss.Timeslices),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// SchedStats returns the scheduling statistics of the process. The kernel
+// reports context switches and run queue times per thread, so they are the
+// sum over the live threads; page faults include exited threads.
+func (p Process) SchedStats() (*SchedStats, error) {
+	st, err := linux.ReadStat(linux.PidPath(p.Pid, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	tids, err := linux.Tids(p.Pid)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &SchedStats{
+		MinorFaults : st.MinFlt,
+		MajorFaults : st.MajFlt,
+	}
+
+	for _, tid := range tids {
+		if err := readSchedStats(linux.TidPath(p.Pid, tid), ss); err != nil {
+			if os.IsNotExist(err) {
+				// The thread exited since the list was read.
+				continue
+			}
+			return nil, err
+		}
+	}
+	return ss, nil
+}
+
+// SchedStats returns the scheduling statistics of the thread.
+func (t Thread) SchedStats() (*SchedStats, error) {
+	dir := linux.TidPath(t.OwnerProcessID, t.ThreadID)
+
+	st, err := linux.ReadStat(dir + "/stat")
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &SchedStats{
+		MinorFaults : st.MinFlt,
+		MajorFaults : st.MajFlt,
+	}
+
+	if err := readSchedStats(dir, ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// readSchedStats adds the context switches and run queue statistics of the
+// task directory dir to ss.
+func readSchedStats(dir string, ss *SchedStats) error {
+	kv, err := linux.ReadKeyValues(dir + "/status")
+	if err != nil {
+		return err
+	}
+
+	vol, _ := strconv.ParseUint(kv["voluntary_ctxt_switches"], 10, 64)
+	nonvol, _ := strconv.ParseUint(kv["nonvoluntary_ctxt_switches"], 10, 64)
+
+	ss.VoluntaryCtxtSwitches += vol
+	ss.NonvoluntaryCtxtSwitches += nonvol
+
+	// Without CONFIG_SCHED_INFO the file does not exist.
+	s, err := linux.ReadString(dir + "/schedstat")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	run, delay, slices, err := parseSchedStat(s)
+	if err != nil {
+		return err
+	}
+
+	ss.RunTime += run
+	ss.RunDelay += delay
+	ss.Timeslices += slices
+	return nil
+}
+
+// parseSchedStat parses /proc/<pid>/schedstat: time on cpu and time waiting
+// on a run queue, both in nanoseconds, and the number of timeslices.
+func parseSchedStat(s string) (run, delay time.Duration, slices uint64, err error) {
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return 0, 0, 0, fmt.Errorf("malformed schedstat: %q", s)
+	}
+
+	var v [3]uint64
+	for i := range v {
+		if v[i], err = strconv.ParseUint(f[i], 10, 64); err != nil {
+			return 0, 0, 0, fmt.Errorf("malformed schedstat: %q", s)
+		}
+	}
+	return time.Duration(v[0]), time.Duration(v[1]), v[2], nil
+}
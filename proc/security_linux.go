@@ -0,0 +1,307 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// Capability is a Linux capability, see capabilities(7).
+type Capability uint
+
+const (
+	CapChown Capability = iota
+	CapDacOverride
+	CapDacReadSearch
+	CapFowner
+	CapFsetid
+	CapKill
+	CapSetgid
+	CapSetuid
+	CapSetpcap
+	CapLinuxImmutable
+	CapNetBindService
+	CapNetBroadcast
+	CapNetAdmin
+	CapNetRaw
+	CapIpcLock
+	CapIpcOwner
+	CapSysModule
+	CapSysRawio
+	CapSysChroot
+	CapSysPtrace
+	CapSysPacct
+	CapSysAdmin
+	CapSysBoot
+	CapSysNice
+	CapSysResource
+	CapSysTime
+	CapSysTtyConfig
+	CapMknod
+	CapLease
+	CapAuditWrite
+	CapAuditControl
+	CapSetfcap
+	CapMacOverride
+	CapMacAdmin
+	CapSyslog
+	CapWakeAlarm
+	CapBlockSuspend
+	CapAuditRead
+	CapPerfmon
+	CapBpf
+	CapCheckpointRestore
+)
+
+var capabilityNames = []string{
+	"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_DAC_READ_SEARCH", "CAP_FOWNER",
+	"CAP_FSETID", "CAP_KILL", "CAP_SETGID", "CAP_SETUID",
+	"CAP_SETPCAP", "CAP_LINUX_IMMUTABLE", "CAP_NET_BIND_SERVICE", "CAP_NET_BROADCAST",
+	"CAP_NET_ADMIN", "CAP_NET_RAW", "CAP_IPC_LOCK", "CAP_IPC_OWNER",
+	"CAP_SYS_MODULE", "CAP_SYS_RAWIO", "CAP_SYS_CHROOT", "CAP_SYS_PTRACE",
+	"CAP_SYS_PACCT", "CAP_SYS_ADMIN", "CAP_SYS_BOOT", "CAP_SYS_NICE",
+	"CAP_SYS_RESOURCE", "CAP_SYS_TIME", "CAP_SYS_TTY_CONFIG", "CAP_MKNOD",
+	"CAP_LEASE", "CAP_AUDIT_WRITE", "CAP_AUDIT_CONTROL", "CAP_SETFCAP",
+	"CAP_MAC_OVERRIDE", "CAP_MAC_ADMIN", "CAP_SYSLOG", "CAP_WAKE_ALARM",
+	"CAP_BLOCK_SUSPEND", "CAP_AUDIT_READ", "CAP_PERFMON", "CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE",
+}
+
+func (c Capability) String() string {
+	if int(c) < len(capabilityNames) {
+		return capabilityNames[c]
+	}
+	return fmt.Sprintf("CAP_%d", uint(c))
+}
+
+//---------------------------------------------------------------------------------------
+
+// CapSet is a set of capabilities, one bit per Capability.
+type CapSet uint64
+
+// NewCapSet returns the set holding caps.
+func NewCapSet(caps ...Capability) CapSet {
+	var cs CapSet
+	for _, c := range caps {
+		cs |= 1 << c
+	}
+	return cs
+}
+
+// Has reports whether c is in the set.
+func (cs CapSet) Has(c Capability) bool {
+	return cs & (1 << c) != 0
+}
+
+// Capabilities returns the members of the set in ascending order.
+func (cs CapSet) Capabilities() []Capability {
+	var ret []Capability
+	for c := Capability(0); c < 64; c++ {
+		if cs.Has(c) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func (cs CapSet) String() string {
+	var names []string
+	for _, c := range cs.Capabilities() {
+		names = append(names, c.String())
+	}
+	return strings.Join(names, ",")
+}
+
+// DangerousCapabilities are the capabilities that let a process escape its
+// confinement or take over the host.
+var DangerousCapabilities = NewCapSet(
+	CapDacOverride,
+	CapDacReadSearch,
+	CapSetuid,
+	CapSetgid,
+	CapNetAdmin,
+	CapNetRaw,
+	CapSysModule,
+	CapSysRawio,
+	CapSysPtrace,
+	CapSysAdmin,
+	CapSysBoot,
+	CapMacOverride,
+	CapMacAdmin,
+	CapBpf,
+	CapPerfmon,
+)
+
+//---------------------------------------------------------------------------------------
+
+// SeccompMode is the seccomp(2) mode of a process.
+type SeccompMode int
+
+const (
+	SeccompDisabled SeccompMode = 0
+	SeccompStrict   SeccompMode = 1
+	SeccompFilter   SeccompMode = 2
+)
+
+func (sm SeccompMode) String() string {
+	switch sm {
+	case SeccompDisabled:
+		return "disabled"
+	case SeccompStrict:
+		return "strict"
+	case SeccompFilter:
+		return "filter"
+	}
+	return fmt.Sprintf("SeccompMode(%d)", int(sm))
+}
+
+type Security struct {
+	Inheritable CapSet      `json:"inheritable"` // Preserved across an execve.
+	Permitted   CapSet      `json:"permitted"`   // Limiting superset of the effective capabilities.
+	Effective   CapSet      `json:"effective"`   // Used for permission checks.
+	Bounding    CapSet      `json:"bounding"`    // Limit of the capabilities gained during execve.
+	Ambient     CapSet      `json:"ambient"`     // Kept across execve of a non-privileged program.
+	Seccomp     SeccompMode `json:"seccomp"`
+	NoNewPrivs  bool        `json:"noNewPrivs"`  // execve cannot grant privileges.
+	Label       string      `json:"label"`       // SELinux context or AppArmor profile, empty without LSM.
+}
+
+func (s Security) GoString() string {
+	ss := []string{"Security{",
+			fmt.Sprintf("  Inheritable : %s", s.Inheritable),
+			fmt.Sprintf("  Permitted   : %s", s.Permitted),
+			fmt.Sprintf("  Effective   : %s", s.Effective),
+			fmt.Sprintf("  Bounding    : %s", s.Bounding),
+			fmt.Sprintf("  Ambient     : %s", s.Ambient),
+			fmt.Sprintf("  Seccomp     : %s", s.Seccomp),
+			fmt.Sprintf("  NoNewPrivs  : %t", s.NoNewPrivs),
+			fmt.Sprintf("  Label       : %s", s.Label),
+			"}",
+	}
+	return strings.Join(ss, "\n")
+}
+
+// Security returns the capability sets, seccomp mode, no_new_privs flag and
+// security module label of the process.
+func (p Process) Security() (*Security, error) {
+	kv, err := linux.ReadKeyValues(linux.PidPath(p.Pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := parseSecurity(kv)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Label, err = p.securityLabel()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func parseSecurity(kv map[string]string) (*Security, error) {
+	var sets [5]CapSet
+
+	for i, key := range []string{"CapInh", "CapPrm", "CapEff", "CapBnd", "CapAmb"} {
+		// CapAmb appeared in Linux 4.3.
+		v, ok := kv[key]
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.ParseUint(v, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s: %q", key, v)
+		}
+		sets[i] = CapSet(n)
+	}
+
+	seccomp, _ := strconv.Atoi(kv["Seccomp"])
+
+	return &Security{
+		Inheritable : sets[0],
+		Permitted   : sets[1],
+		Effective   : sets[2],
+		Bounding    : sets[3],
+		Ambient     : sets[4],
+		Seccomp     : SeccompMode(seccomp),
+		NoNewPrivs  : kv["NoNewPrivs"] == "1",
+	}, nil
+}
+
+// securityLabel reads the label set by the active security module. Reading
+// fails with EINVAL when no module provides one.
+func (p Process) securityLabel() (string, error) {
+	for _, path := range []string{
+		linux.PidPath(p.Pid, "attr", "current"),
+		linux.PidPath(p.Pid, "attr", "apparmor", "current"),
+	} {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) || isErrno(err, syscall.EINVAL) {
+				continue
+			}
+			return "", err
+		}
+
+		if label := strings.TrimSpace(strings.TrimRight(string(b), "\x00")); label != "" {
+			return label, nil
+		}
+	}
+	return "", nil
+}
+
+func isErrno(err error, errno syscall.Errno) bool {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	return err == errno
+}
+
+//---------------------------------------------------------------------------------------
+
+type PrivilegedProcess struct {
+	*Process
+	Capabilities CapSet `json:"capabilities"` // The dangerous capabilities held.
+}
+
+// Privileged lists the processes whose permitted set holds any of the
+// DangerousCapabilities. Processes whose status cannot be read are left out.
+func Privileged() ([]*PrivilegedProcess, error) {
+	procs, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*PrivilegedProcess
+	for _, p := range procs {
+		kv, err := linux.ReadKeyValues(linux.PidPath(p.Pid, "status"))
+		if err != nil {
+			continue
+		}
+
+		s, err := parseSecurity(kv)
+		if err != nil {
+			continue
+		}
+
+		if caps := s.Permitted & DangerousCapabilities; caps != 0 {
+			ret = append(ret, &PrivilegedProcess{
+				Process      : p,
+				Capabilities : caps,
+			})
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,248 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/entuerto/sysmon"
+)
+
+// Selector matches processes on their attributes. Empty fields match any
+// process, set fields must all match.
+//
+// A selector can be parsed from a string of space separated terms, for
+// example "user=www-data name~^php rss>200M":
+//
+//	name=GLOB     shell pattern matched against the name
+//	name~REGEXP   regular expression matched against the name
+//	cmd=TEXT      text contained in the command line
+//	user=NAME     user name, with or without the Windows domain
+//	ppid=PID      parent process id
+//	cgroup=PATH   cgroup path or one of its ancestors, Linux only
+//	state=STATE   status such as "running" or "zombie"
+//	rss>SIZE      resident set size of at least SIZE, like 512K, 200M or 1.5G
+//	cpu>PERCENT   CPU usage of at least PERCENT, 100 being one CPU
+//
+// The > comparisons include the bound, so there is no >= form. String
+// returns the same syntax, with sizes in bytes.
+type Selector struct {
+	Name     string         `json:"name"`
+	NameRE   *regexp.Regexp `json:"-"`
+	CmdLine  string         `json:"cmdLine"`
+	User     string         `json:"user"`
+	ParentId uint32         `json:"ppid"` // 0 for any
+	Cgroup   string         `json:"cgroup"`
+	State    string         `json:"state"`
+	MinRSS   sysmon.Size    `json:"minRss"`
+	MinCPU   float64        `json:"minCpu"`
+}
+
+// ParseSelector parses a selector from its string form.
+func ParseSelector(s string) (*Selector, error) {
+	sel := &Selector{}
+
+	for _, term := range strings.Fields(s) {
+		i := strings.IndexAny(term, "=~>")
+		if i <= 0 {
+			return nil, fmt.Errorf("selector: malformed term %q", term)
+		}
+		key, op, value := term[:i], term[i:i+1], term[i+1:]
+		if op == ">" && strings.HasPrefix(value, "=") {
+			return nil, fmt.Errorf("selector: %q: > already means at least, there is no >=", term)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("selector: missing value in %q", term)
+		}
+
+		if err := sel.set(key, op, value); err != nil {
+			return nil, fmt.Errorf("selector: %q: %v", term, err)
+		}
+	}
+	return sel, nil
+}
+
+func (sel *Selector) set(key, op, value string) error {
+	switch key + op {
+	case "name=":
+		if _, err := path.Match(value, ""); err != nil {
+			return err
+		}
+		sel.Name = value
+	case "name~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return err
+		}
+		sel.NameRE = re
+	case "cmd=":
+		sel.CmdLine = value
+	case "user=":
+		sel.User = value
+	case "ppid=":
+		pid, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		sel.ParentId = uint32(pid)
+	case "cgroup=":
+		sel.Cgroup = value
+	case "state=":
+		sel.State = value
+	case "rss>":
+		size, err := parseSize(value)
+		if err != nil {
+			return err
+		}
+		sel.MinRSS = size
+	case "cpu>":
+		cpu, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return err
+		}
+		sel.MinCPU = cpu
+	default:
+		return fmt.Errorf("unknown term %s%s", key, op)
+	}
+	return nil
+}
+
+func (sel Selector) String() string {
+	var s []string
+	if sel.Name != "" {
+		s = append(s, "name=" + sel.Name)
+	}
+	if sel.NameRE != nil {
+		s = append(s, "name~" + sel.NameRE.String())
+	}
+	if sel.CmdLine != "" {
+		s = append(s, "cmd=" + sel.CmdLine)
+	}
+	if sel.User != "" {
+		s = append(s, "user=" + sel.User)
+	}
+	if sel.ParentId != 0 {
+		s = append(s, fmt.Sprintf("ppid=%d", sel.ParentId))
+	}
+	if sel.Cgroup != "" {
+		s = append(s, "cgroup=" + sel.Cgroup)
+	}
+	if sel.State != "" {
+		s = append(s, "state=" + sel.State)
+	}
+	if sel.MinRSS != 0 {
+		s = append(s, fmt.Sprintf("rss>%d", uint64(sel.MinRSS)))
+	}
+	if sel.MinCPU != 0 {
+		s = append(s, "cpu>" + strconv.FormatFloat(sel.MinCPU, 'g', -1, 64))
+	}
+	return strings.Join(s, " ")
+}
+
+// Match reports whether the process matches all the set fields. RSS and CPU
+// usage are taken from ps, so they are only meaningful for sampled processes.
+func (sel Selector) Match(ps *ProcessStat) bool {
+	p := ps.Process
+
+	if sel.Name != "" {
+		if ok, _ := path.Match(sel.Name, p.Name); !ok {
+			return false
+		}
+	}
+	if sel.NameRE != nil && !sel.NameRE.MatchString(p.Name) {
+		return false
+	}
+	if sel.CmdLine != "" && !strings.Contains(p.CmdLine, sel.CmdLine) {
+		return false
+	}
+	if sel.User != "" && !matchUser(sel.User, p.UserName) {
+		return false
+	}
+	if sel.ParentId != 0 && p.ParentId != sel.ParentId {
+		return false
+	}
+	if sel.State != "" && !strings.EqualFold(p.Status, sel.State) {
+		return false
+	}
+	if ps.RSS < sel.MinRSS || ps.CPUPercent < sel.MinCPU {
+		return false
+	}
+	// Last, it is the only test reading more than p.
+	if sel.Cgroup != "" && !matchCgroup(sel.Cgroup, cgroupPaths(p)) {
+		return false
+	}
+	return true
+}
+
+// Select returns the processes of stats matching sel.
+func (sel Selector) Select(stats []*ProcessStat) []*ProcessStat {
+	var ret []*ProcessStat
+	for _, ps := range stats {
+		if sel.Match(ps) {
+			ret = append(ret, ps)
+		}
+	}
+	return ret
+}
+
+// matchUser compares user names, ignoring the domain of "DOMAIN\user" when
+// the selector has none.
+func matchUser(want, name string) bool {
+	if strings.EqualFold(want, name) {
+		return true
+	}
+	if i := strings.LastIndexByte(name, '\\'); i >= 0 && !strings.Contains(want, `\`) {
+		return strings.EqualFold(want, name[i+1:])
+	}
+	return false
+}
+
+// matchCgroup reports whether one of paths is want or below it.
+func matchCgroup(want string, paths []string) bool {
+	want = strings.TrimSuffix(want, "/")
+	for _, p := range paths {
+		if p == want || strings.HasPrefix(p, want + "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSize parses a byte count with an optional binary unit suffix: K, M,
+// G, T, P or E, optionally followed by B or iB.
+func parseSize(s string) (sysmon.Size, error) {
+	num := strings.TrimRight(strings.ToUpper(s), "IB")
+	unit := sysmon.Size(1)
+
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'K':
+			unit = sysmon.KB
+		case 'M':
+			unit = sysmon.MB
+		case 'G':
+			unit = sysmon.GB
+		case 'T':
+			unit = sysmon.TB
+		case 'P':
+			unit = sysmon.PB
+		case 'E':
+			unit = sysmon.EB
+		}
+		if unit != 1 {
+			num = num[:n-1]
+		}
+	}
+
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return sysmon.Size(v * float64(unit)), nil
+}
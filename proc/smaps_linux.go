@@ -0,0 +1,263 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/entuerto/sysmon"
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+type MemoryMap struct {
+	Start          uint64      `json:"start"`
+	End            uint64      `json:"end"`
+	Perms          string      `json:"perms"`  // rwxp or rwxs, empty when grouped
+	Offset         uint64      `json:"offset"` // offset in the backing file
+	Device         string      `json:"device"` // major:minor of the backing file
+	Inode          uint64      `json:"inode"`
+	Path           string      `json:"path"`   // backing file, or a pseudo path such as [heap], empty for anonymous memory
+	Size           sysmon.Size `json:"size"`
+	Rss            sysmon.Size `json:"rss"`
+	Pss            sysmon.Size `json:"pss"`    // Rss with shared pages divided among the processes mapping them.
+	SharedClean    sysmon.Size `json:"sharedClean"`
+	SharedDirty    sysmon.Size `json:"sharedDirty"`
+	PrivateClean   sysmon.Size `json:"privateClean"`
+	PrivateDirty   sysmon.Size `json:"privateDirty"`
+	Referenced     sysmon.Size `json:"referenced"`
+	Anonymous      sysmon.Size `json:"anonymous"`
+	Swap           sysmon.Size `json:"swap"`
+	AnonHugePages  sysmon.Size `json:"anonHugePages"`
+	Locked         sysmon.Size `json:"locked"`
+}
+
+func (mm MemoryMap) GoString() string {
+	s := []string{"MemoryMap{",
+			fmt.Sprintf("  Address       : %x-%x", mm.Start, mm.End),
+			fmt.Sprintf("  Perms         : %s", mm.Perms),
+			fmt.Sprintf("  Offset        : %x", mm.Offset),
+			fmt.Sprintf("  Device        : %s", mm.Device),
+			fmt.Sprintf("  Inode         : %d", mm.Inode),
+			fmt.Sprintf("  Path          : %s", mm.Path),
+			fmt.Sprintf("  Size          : %s", mm.Size),
+			fmt.Sprintf("  Rss           : %s", mm.Rss),
+			fmt.Sprintf("  Pss           : %s", mm.Pss),
+			fmt.Sprintf("  SharedClean   : %s", mm.SharedClean),
+			fmt.Sprintf("  SharedDirty   : %s", mm.SharedDirty),
+			fmt.Sprintf("  PrivateClean  : %s", mm.PrivateClean),
+			fmt.Sprintf("  PrivateDirty  : %s", mm.PrivateDirty),
+			fmt.Sprintf("  Referenced    : %s", mm.Referenced),
+			fmt.Sprintf("  Anonymous     : %s", mm.Anonymous),
+			fmt.Sprintf("  Swap          : %s", mm.Swap),
+			fmt.Sprintf("  AnonHugePages : %s", mm.AnonHugePages),
+			fmt.Sprintf("  Locked        : %s", mm.Locked),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// add accumulates the counters of o into mm.
+func (mm *MemoryMap) add(o *MemoryMap) {
+	mm.Size += o.Size
+	mm.Rss += o.Rss
+	mm.Pss += o.Pss
+	mm.SharedClean += o.SharedClean
+	mm.SharedDirty += o.SharedDirty
+	mm.PrivateClean += o.PrivateClean
+	mm.PrivateDirty += o.PrivateDirty
+	mm.Referenced += o.Referenced
+	mm.Anonymous += o.Anonymous
+	mm.Swap += o.Swap
+	mm.AnonHugePages += o.AnonHugePages
+	mm.Locked += o.Locked
+}
+
+// MemoryMaps returns the mappings of the process from /proc/<pid>/smaps.
+// When grouped is true the mappings are aggregated by path, in the order they
+// first appear: Start and End span all the mappings of the path and Perms and
+// Offset are left empty. Anonymous mappings have no path and are kept
+// separate; pseudo paths such as [heap] or [stack] are grouped.
+func (p Process) MemoryMaps(grouped bool) ([]*MemoryMap, error) {
+	lines, err := linux.ReadLines(linux.PidPath(p.Pid, "smaps"))
+	if err != nil {
+		return nil, err
+	}
+
+	maps, err := parseSmaps(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	if grouped {
+		maps = groupMemoryMaps(maps)
+	}
+	return maps, nil
+}
+
+// HugePageUsage is the huge page memory of a process.
+type HugePageUsage struct {
+	AnonHugePages sysmon.Size `json:"anonHugePages"` // transparent huge pages backing anonymous memory
+	HugetlbPages  sysmon.Size `json:"hugetlbPages"`  // pages from the persistent huge page pools, through hugetlbfs or MAP_HUGETLB
+}
+
+// HugePages returns the huge page memory of the process, from
+// /proc/<pid>/smaps_rollup, or smaps before Linux 4.14, and
+// /proc/<pid>/status.
+func (p Process) HugePages() (*HugePageUsage, error) {
+	lines, err := linux.ReadLines(linux.PidPath(p.Pid, "smaps_rollup"))
+	if os.IsNotExist(err) {
+		lines, err = linux.ReadLines(linux.PidPath(p.Pid, "smaps"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	maps, err := parseSmaps(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	hu := &HugePageUsage{}
+	for _, m := range maps {
+		hu.AnonHugePages += m.AnonHugePages
+	}
+
+	// Since Linux 4.4.
+	kv, err := linux.ReadKeyValues(linux.PidPath(p.Pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+	hu.HugetlbPages = sysmon.Size(linux.ParseKB(kv["HugetlbPages"]))
+	return hu, nil
+}
+
+func parseSmaps(lines []string) ([]*MemoryMap, error) {
+	var (
+		ret []*MemoryMap
+		cur *MemoryMap
+	)
+
+	for _, l := range lines {
+		i := strings.IndexByte(l, ':')
+		// Counter lines start with "Key:", headers with "address perms ...".
+		if i > 0 && !strings.Contains(l[:i], " ") {
+			if cur == nil {
+				return nil, fmt.Errorf("malformed smaps: %q", l)
+			}
+			setSmapsField(cur, l[:i], l[i+1:])
+			continue
+		}
+
+		m, err := parseMapsHeader(l)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, m)
+		cur = m
+	}
+	return ret, nil
+}
+
+// parseMapsHeader parses "address perms offset dev inode [path]".
+func parseMapsHeader(l string) (*MemoryMap, error) {
+	f := strings.Fields(l)
+	if len(f) < 5 {
+		return nil, fmt.Errorf("malformed smaps header: %q", l)
+	}
+
+	addr := strings.SplitN(f[0], "-", 2)
+	if len(addr) != 2 {
+		return nil, fmt.Errorf("malformed smaps header: %q", l)
+	}
+	start, err := strconv.ParseUint(addr[0], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed smaps header: %q", l)
+	}
+	end, err := strconv.ParseUint(addr[1], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed smaps header: %q", l)
+	}
+
+	offset, _ := strconv.ParseUint(f[2], 16, 64)
+	inode, _ := strconv.ParseUint(f[4], 10, 64)
+
+	return &MemoryMap{
+		Start  : start,
+		End    : end,
+		Perms  : f[1],
+		Offset : offset,
+		Device : f[3],
+		Inode  : inode,
+		Path   : strings.Join(f[5:], " "),
+	}, nil
+}
+
+func setSmapsField(m *MemoryMap, key, value string) {
+	v := sysmon.Size(linux.ParseKB(value))
+
+	switch key {
+	case "Size":
+		m.Size = v
+	case "Rss":
+		m.Rss = v
+	case "Pss":
+		m.Pss = v
+	case "Shared_Clean":
+		m.SharedClean = v
+	case "Shared_Dirty":
+		m.SharedDirty = v
+	case "Private_Clean":
+		m.PrivateClean = v
+	case "Private_Dirty":
+		m.PrivateDirty = v
+	case "Referenced":
+		m.Referenced = v
+	case "Anonymous":
+		m.Anonymous = v
+	case "Swap":
+		m.Swap = v
+	case "AnonHugePages":
+		m.AnonHugePages = v
+	case "Locked":
+		m.Locked = v
+	}
+}
+
+func groupMemoryMaps(maps []*MemoryMap) []*MemoryMap {
+	var ret []*MemoryMap
+	groups := make(map[string]*MemoryMap)
+
+	for _, m := range maps {
+		if m.Path == "" {
+			ret = append(ret, m)
+			continue
+		}
+
+		g, ok := groups[m.Path]
+		if !ok {
+			g = &MemoryMap{
+				Start  : m.Start,
+				End    : m.End,
+				Device : m.Device,
+				Inode  : m.Inode,
+				Path   : m.Path,
+			}
+			groups[m.Path] = g
+			ret = append(ret, g)
+		}
+
+		if m.Start < g.Start {
+			g.Start = m.Start
+		}
+		if m.End > g.End {
+			g.End = m.End
+		}
+		g.add(m)
+	}
+	return ret
+}
@@ -0,0 +1,145 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// WaitChannel returns the kernel function the thread is sleeping in, or an
+// empty string when it is running.
+func (t Thread) WaitChannel() (string, error) {
+	s, err := linux.ReadString(linux.TidPath(t.OwnerProcessID, t.ThreadID, "wchan"))
+	if err != nil {
+		return "", err
+	}
+	if s == "0" {
+		return "", nil
+	}
+	return s, nil
+}
+
+// KernelStack returns the kernel call stack of the thread, innermost frame
+// first, as "function+offset/size". Reading it requires CAP_SYS_ADMIN.
+func (t Thread) KernelStack() ([]string, error) {
+	lines, err := linux.ReadLines(linux.TidPath(t.OwnerProcessID, t.ThreadID, "stack"))
+	if err != nil {
+		return nil, err
+	}
+	return parseKernelStack(lines), nil
+}
+
+// parseKernelStack strips the "[<address>] " prefix of the frames, the
+// addresses are hidden from user space anyway.
+func parseKernelStack(lines []string) []string {
+	var ret []string
+	for _, l := range lines {
+		if strings.HasPrefix(l, "[<") {
+			if i := strings.Index(l, "] "); i > 0 {
+				l = l[i+2:]
+			}
+		}
+		if l = strings.TrimSpace(l); l != "" {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+//---------------------------------------------------------------------------------------
+
+// SyscallInfo describes the system call a thread is blocked in.
+type SyscallInfo struct {
+	Running bool      `json:"running"` // The thread is running, other fields are unset.
+	Number  int       `json:"number"`  // -1 when the thread is blocked outside of a system call.
+	Name    string    `json:"name"`    // empty for calls missing from the table of the architecture
+	Args    [6]uint64 `json:"args"`
+	SP      uint64    `json:"sp"`      // user stack pointer
+	PC      uint64    `json:"pc"`      // user program counter
+}
+
+func (si SyscallInfo) GoString() string {
+	s := []string{"SyscallInfo{",
+			fmt.Sprintf("  Running : %v", si.Running),
+			fmt.Sprintf("  Number  : %d", si.Number),
+			fmt.Sprintf("  Name    : %s", si.Name),
+			fmt.Sprintf("  Args    : %#x", si.Args),
+			fmt.Sprintf("  SP      : %#x", si.SP),
+			fmt.Sprintf("  PC      : %#x", si.PC),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// String formats the call like strace does, "read(0x3, 0x7ffd5e1c, 0x1000, ...)".
+func (si SyscallInfo) String() string {
+	switch {
+	case si.Running:
+		return "running"
+	case si.Number < 0:
+		return "not in a system call"
+	}
+
+	name := si.Name
+	if name == "" {
+		name = fmt.Sprintf("syscall_%d", si.Number)
+	}
+
+	args := make([]string, len(si.Args))
+	for i, a := range si.Args {
+		args[i] = fmt.Sprintf("%#x", a)
+	}
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Syscall returns the system call the thread is blocked in. The arguments
+// are raw register values, reading them requires the right to ptrace the
+// process.
+func (t Thread) Syscall() (*SyscallInfo, error) {
+	s, err := linux.ReadString(linux.TidPath(t.OwnerProcessID, t.ThreadID, "syscall"))
+	if err != nil {
+		return nil, err
+	}
+	return parseSyscall(s)
+}
+
+// parseSyscall parses "running", "-1 sp pc" or "nr arg1 ... arg6 sp pc".
+func parseSyscall(s string) (*SyscallInfo, error) {
+	if s == "running" {
+		return &SyscallInfo{Running: true, Number: -1}, nil
+	}
+
+	f := strings.Fields(s)
+	if len(f) != 3 && len(f) != 9 {
+		return nil, fmt.Errorf("malformed syscall: %q", s)
+	}
+
+	nr, err := strconv.Atoi(f[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed syscall: %q", s)
+	}
+
+	var regs []uint64
+	for _, v := range f[1:] {
+		r, err := strconv.ParseUint(strings.TrimPrefix(v, "0x"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed syscall: %q", s)
+		}
+		regs = append(regs, r)
+	}
+
+	si := &SyscallInfo{
+		Number : nr,
+		Name   : syscallNames[nr],
+		SP     : regs[len(regs)-2],
+		PC     : regs[len(regs)-1],
+	}
+	copy(si.Args[:], regs[:len(regs)-2])
+	return si, nil
+}
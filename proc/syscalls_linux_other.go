@@ -0,0 +1,11 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && !amd64 && !arm64
+// +build linux,!amd64,!arm64
+
+package proc
+
+// No system call table for this architecture, calls are reported by number.
+var syscallNames = map[int]string{}
@@ -0,0 +1,210 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/entuerto/sysmon"
+)
+
+// SortKey selects the metric used to rank processes.
+type SortKey int
+
+const (
+	SortByCPU SortKey = iota + 1
+	SortByRSS
+	SortByReadRate
+	SortByWriteRate
+	SortByThreads
+	SortByFDs
+)
+
+func (sk SortKey) String() string {
+	switch sk {
+	case SortByCPU:
+		return "cpu"
+	case SortByRSS:
+		return "rss"
+	case SortByReadRate:
+		return "read"
+	case SortByWriteRate:
+		return "write"
+	case SortByThreads:
+		return "threads"
+	case SortByFDs:
+		return "fds"
+	}
+	return fmt.Sprintf("SortKey(%d)", int(sk))
+}
+
+//---------------------------------------------------------------------------------------
+
+type ProcessStat struct {
+	*Process
+	CPUPercent float64     `json:"cpuPercent"` // 100 is one CPU fully used over the interval.
+	RSS        sysmon.Size `json:"rss"`
+	ReadRate   float64     `json:"readRate"`   // bytes read per second
+	WriteRate  float64     `json:"writeRate"`  // bytes written per second
+	Threads    uint32      `json:"threads"`
+	FDs        uint32      `json:"fds"`        // open file descriptors, handles on Windows
+}
+
+func (ps ProcessStat) GoString() string {
+	s := []string{"ProcessStat{",
+			fmt.Sprintf("  Pid        : %d", ps.Pid),
+			fmt.Sprintf("  Name       : %s", ps.Name),
+			fmt.Sprintf("  CPUPercent : %.2f", ps.CPUPercent),
+			fmt.Sprintf("  RSS        : %s", ps.RSS),
+			fmt.Sprintf("  ReadRate   : %s/s", sysmon.Size(ps.ReadRate)),
+			fmt.Sprintf("  WriteRate  : %s/s", sysmon.Size(ps.WriteRate)),
+			fmt.Sprintf("  Threads    : %d", ps.Threads),
+			fmt.Sprintf("  FDs        : %d", ps.FDs),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// Value returns the metric selected by key.
+func (ps ProcessStat) Value(key SortKey) float64 {
+	switch key {
+	case SortByCPU:
+		return ps.CPUPercent
+	case SortByRSS:
+		return float64(ps.RSS)
+	case SortByReadRate:
+		return ps.ReadRate
+	case SortByWriteRate:
+		return ps.WriteRate
+	case SortByThreads:
+		return float64(ps.Threads)
+	case SortByFDs:
+		return float64(ps.FDs)
+	}
+	return 0
+}
+
+// Sample measures every process twice, interval apart, and computes CPU
+// usage and I/O rates over the interval. Processes that exited in between
+// are left out, processes that started in between have zero rates. Metrics
+// the caller is not allowed to read are zero. The caller owns the handles of
+// the returned processes and releases them with Release.
+func Sample(ctx context.Context, interval time.Duration) ([]*ProcessStat, error) {
+	first, err := sampleProcesses(true)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-time.After(interval):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	second, err := sampleProcesses(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*ProcessStat
+	for key, cur := range second {
+		ps := &ProcessStat{
+			Process : cur.p,
+			RSS     : cur.rss,
+			Threads : cur.p.ThreadCount,
+			FDs     : cur.p.HandleCount,
+		}
+
+		if prev, ok := first[key]; ok {
+			elapsed := cur.at.Sub(prev.at).Seconds()
+			if elapsed > 0 {
+				ps.CPUPercent = (cur.cpu - prev.cpu).Seconds() / elapsed * 100
+				if cur.read >= prev.read {
+					ps.ReadRate = float64(cur.read - prev.read) / elapsed
+				}
+				if cur.write >= prev.write {
+					ps.WriteRate = float64(cur.write - prev.write) / elapsed
+				}
+			}
+		}
+		ret = append(ret, ps)
+	}
+	return ret, nil
+}
+
+// Top returns the n processes with the highest value of the metric by,
+// highest first, sampled over interval. The caller owns the handles of the
+// returned processes, the others are released.
+func Top(ctx context.Context, by SortKey, n int, interval time.Duration) ([]*ProcessStat, error) {
+	stats, err := Sample(ctx, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		vi, vj := stats[i].Value(by), stats[j].Value(by)
+		if vi != vj {
+			return vi > vj
+		}
+		return stats[i].Pid < stats[j].Pid
+	})
+
+	if n >= 0 && n < len(stats) {
+		for _, ps := range stats[n:] {
+			ps.Release()
+		}
+		stats = stats[:n]
+	}
+	return stats, nil
+}
+
+type sample struct {
+	p     *Process
+	at    time.Time
+	cpu   time.Duration
+	rss   sysmon.Size
+	read  sysmon.Size
+	write sysmon.Size
+}
+
+// sampleProcesses measures every process. With release, the handles are
+// released and only snapshots are kept.
+func sampleProcesses(release bool) (map[ProcessKey]*sample, error) {
+	procs, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[ProcessKey]*sample, len(procs))
+	for _, p := range procs {
+		s := &sample{
+			p  : p,
+			at : time.Now(),
+		}
+
+		if u, err := p.Usage(); err == nil {
+			s.cpu = u.KernelTime + u.UserTime
+		}
+		if m, err := p.MemoryInfo(); err == nil {
+			s.rss = m.WorkingSetSize
+		}
+		if io, err := p.IOCounters(); err == nil {
+			s.read = io.ReadBytes
+			s.write = io.WriteBytes
+		}
+
+		if release {
+			p.Release()
+			p.handle = 0
+		}
+
+		ret[p.Key()] = s
+	}
+	return ret, nil
+}
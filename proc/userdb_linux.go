@@ -0,0 +1,119 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// userDB resolves user and group ids by reading /etc/passwd and /etc/group
+// directly, which works without cgo. The files are parsed once and shared
+// by every lookup of a process scan; they are read again when they change.
+type userDB struct {
+	passwdFile string
+	groupFile  string
+
+	mu        sync.Mutex
+	checked   time.Time
+	passwdMod time.Time
+	groupMod  time.Time
+	users     map[uint32]string
+	groups    map[uint32]string
+}
+
+// How often the files are checked for changes.
+const userDBCheckInterval = time.Second
+
+var users = &userDB{
+	passwdFile : "/etc/passwd",
+	groupFile  : "/etc/group",
+}
+
+// User returns the name of uid, or uid as a string when it is unknown.
+func (db *userDB) User(uid uint32) string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.refresh()
+	if name, ok := db.users[uid]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(uid), 10)
+}
+
+// Group returns the name of gid, or gid as a string when it is unknown.
+func (db *userDB) Group(gid uint32) string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.refresh()
+	if name, ok := db.groups[gid]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(gid), 10)
+}
+
+func (db *userDB) refresh() {
+	now := time.Now()
+	if db.users != nil && now.Sub(db.checked) < userDBCheckInterval {
+		return
+	}
+	db.checked = now
+
+	if mod := modTime(db.passwdFile); db.users == nil || !mod.Equal(db.passwdMod) {
+		db.users = readIDFile(db.passwdFile)
+		db.passwdMod = mod
+	}
+	if mod := modTime(db.groupFile); db.groups == nil || !mod.Equal(db.groupMod) {
+		db.groups = readIDFile(db.groupFile)
+		db.groupMod = mod
+	}
+}
+
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// readIDFile maps the third field of /etc/passwd or /etc/group lines, the
+// id, to the first, the name. A missing file yields an empty map.
+func readIDFile(path string) map[uint32]string {
+	ret := make(map[uint32]string)
+
+	lines, err := linux.ReadLines(path)
+	if err != nil {
+		return ret
+	}
+
+	for _, l := range lines {
+		if l == "" || l[0] == '#' {
+			continue
+		}
+
+		f := strings.SplitN(l, ":", 4)
+		if len(f) < 3 {
+			continue
+		}
+
+		id, err := strconv.ParseUint(f[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		// The first entry wins, as with getpwuid(3).
+		if _, ok := ret[uint32(id)]; !ok {
+			ret[uint32(id)] = f[0]
+		}
+	}
+	return ret
+}
@@ -0,0 +1,151 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type EventType int
+
+const (
+	ProcessStarted EventType = iota + 1
+	ProcessExited
+	ProcessExec // The process replaced its program with execve.
+)
+
+func (et EventType) String() string {
+	switch et {
+	case ProcessStarted:
+		return "Started"
+	case ProcessExited:
+		return "Exited"
+	case ProcessExec:
+		return "Exec"
+	}
+	return fmt.Sprintf("EventType(%d)", int(et))
+}
+
+type ProcessEvent struct {
+	Type    EventType  `json:"type"`
+	Key     ProcessKey `json:"key"`
+	Process *Process   `json:"process"` // Snapshot of the process, the last one known for ProcessExited.
+	Time    time.Time  `json:"time"`    // When the event was observed.
+}
+
+func (pe ProcessEvent) GoString() string {
+	s := []string{"ProcessEvent{",
+			fmt.Sprintf("  Type    : %s", pe.Type),
+			fmt.Sprintf("  Key     : %s", pe.Key),
+			fmt.Sprintf("  Name    : %s", pe.Process.Name),
+			fmt.Sprintf("  Time    : %s", pe.Time),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// Watch reports processes starting, exiting and executing a new program
+// until ctx is done, then closes the channel. Processes running when Watch
+// is called produce no event.
+//
+// On Linux, when the caller has CAP_NET_ADMIN and UseProcConnector is set,
+// events come from the netlink process connector as they happen. Otherwise
+// the process list is scanned every interval and successive scans are
+// compared; processes living less than interval may go unnoticed.
+func Watch(ctx context.Context, interval time.Duration) <-chan ProcessEvent {
+	ch := make(chan ProcessEvent)
+	connector := UseProcConnector
+
+	go func() {
+		defer close(ch)
+
+		if connector {
+			if err := watchConnector(ctx, ch); err == nil {
+				return
+			}
+		}
+		pollWatch(ctx, interval, ch)
+	}()
+
+	return ch
+}
+
+// UseProcConnector lets Watch use the process connector on Linux. Watch
+// checks that the connector reports a short-lived child before relying on
+// it, as it can be joined from a network or user namespace other than the
+// initial one but delivers no event there. Clear it to always scan.
+var UseProcConnector = true
+
+func pollWatch(ctx context.Context, interval time.Duration, ch chan<- ProcessEvent) {
+	known, _ := scanProcesses()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := scanProcesses()
+		if err != nil {
+			continue
+		}
+
+		for _, ev := range diffProcesses(known, current, time.Now()) {
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+		known = current
+	}
+}
+
+// scanProcesses lists the processes by identity key. Handles are released
+// right away, the processes are only kept as snapshots.
+func scanProcesses() (map[ProcessKey]*Process, error) {
+	procs, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[ProcessKey]*Process, len(procs))
+	for _, p := range procs {
+		p.Release()
+		p.handle = 0
+		ret[p.Key()] = p
+	}
+	return ret, nil
+}
+
+// diffProcesses compares two scans. Keys include the start time, so a pid
+// reused between the scans shows up as an exit and a start.
+func diffProcesses(prev, cur map[ProcessKey]*Process, now time.Time) []ProcessEvent {
+	var events []ProcessEvent
+
+	for key, p := range prev {
+		if _, ok := cur[key]; !ok {
+			events = append(events, ProcessEvent{Type: ProcessExited, Key: key, Process: p, Time: now})
+		}
+	}
+
+	for key, p := range cur {
+		old, ok := prev[key]
+		switch {
+		case !ok:
+			events = append(events, ProcessEvent{Type: ProcessStarted, Key: key, Process: p, Time: now})
+		case old.Executable != p.Executable || old.CmdLine != p.CmdLine:
+			events = append(events, ProcessEvent{Type: ProcessExec, Key: key, Process: p, Time: now})
+		}
+	}
+	return events
+}
@@ -0,0 +1,200 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// connectorProbeTimeout is how long probeConnector waits for the events of
+// its child.
+var connectorProbeTimeout = time.Second
+
+// errNoConnectorEvents is returned when the connector was joined but did
+// not report the probe child.
+var errNoConnectorEvents = errors.New("process connector delivers no events")
+
+// watchConnector delivers the events of the netlink process connector until
+// ctx is done. It fails right away when the connector cannot be joined or
+// does not deliver events.
+func watchConnector(ctx context.Context, ch chan<- ProcessEvent) error {
+	fd, err := linux.OpenProcConnector()
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	buf := make([]byte, 16*1024)
+	if err := probeConnector(fd, buf); err != nil {
+		return err
+	}
+
+	scan, err := scanProcesses()
+	if err != nil {
+		return err
+	}
+
+	// The connector only reports pids, keep the last snapshot of each process.
+	known := make(map[uint32]*Process, len(scan))
+	for _, p := range scan {
+		known[p.Pid] = p
+	}
+
+	send := func(ev ProcessEvent) bool {
+		select {
+		case ch <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		// Wake up regularly to check whether ctx is done.
+		ev, err := linux.Poll(fd, linux.POLLIN, 100*time.Millisecond)
+		if err != nil && err != syscall.EINTR {
+			return err
+		}
+		if ev == 0 {
+			continue
+		}
+
+		events, err := linux.ReadProcEvents(fd, buf)
+		if err == syscall.ENOBUFS {
+			// Events were dropped, catch up with a scan.
+			if !resync(known, send) {
+				return nil
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			// Events about threads other than the main one are ignored.
+			if e.Pid != e.Tgid {
+				continue
+			}
+
+			var pe ProcessEvent
+			switch e.What {
+			case linux.PROC_EVENT_FORK:
+				p, err := newProcess(e.Tgid)
+				if err != nil {
+					// Already gone, the exit event follows.
+					p = &Process{Pid: e.Tgid, ParentId: e.ParentTgid}
+				}
+				known[p.Pid] = p
+				pe = ProcessEvent{Type: ProcessStarted, Key: p.Key(), Process: p}
+
+			case linux.PROC_EVENT_EXEC:
+				p, err := newProcess(e.Tgid)
+				if err != nil {
+					continue
+				}
+				known[p.Pid] = p
+				pe = ProcessEvent{Type: ProcessExec, Key: p.Key(), Process: p}
+
+			case linux.PROC_EVENT_EXIT:
+				p, ok := known[e.Tgid]
+				if !ok {
+					p = &Process{Pid: e.Tgid}
+				}
+				delete(known, e.Tgid)
+				pe = ProcessEvent{Type: ProcessExited, Key: p.Key(), Process: p}
+			}
+
+			pe.Time = time.Now()
+			if !send(pe) {
+				return nil
+			}
+		}
+	}
+}
+
+// resync rescans the processes after events were lost and sends the
+// difference with the known ones.
+func resync(known map[uint32]*Process, send func(ProcessEvent) bool) bool {
+	cur, err := scanProcesses()
+	if err != nil {
+		return true
+	}
+
+	prev := make(map[ProcessKey]*Process, len(known))
+	for _, p := range known {
+		prev[p.Key()] = p
+	}
+
+	for k := range known {
+		delete(known, k)
+	}
+	for _, p := range cur {
+		known[p.Pid] = p
+	}
+
+	for _, ev := range diffProcesses(prev, cur, time.Now()) {
+		if !send(ev) {
+			return false
+		}
+	}
+	return true
+}
+
+// probeConnector starts a child that exits right away and waits for the
+// connector to report it. Joining the connector succeeds in a network or user
+// namespace other than the initial one, but no event is ever delivered there.
+func probeConnector(fd int, buf []byte) error {
+	cmd := exec.Command("/bin/sh", "-c", "exit 0")
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	pid := uint32(cmd.Process.Pid)
+	defer cmd.Wait()
+
+	deadline := time.Now().Add(connectorProbeTimeout)
+	for {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return errNoConnectorEvents
+		}
+
+		ev, err := linux.Poll(fd, linux.POLLIN, timeout)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if ev == 0 {
+			continue
+		}
+
+		events, err := linux.ReadProcEvents(fd, buf)
+		if err == syscall.ENOBUFS {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if e.Tgid == pid {
+				return nil
+			}
+		}
+	}
+}
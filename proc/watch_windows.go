@@ -0,0 +1,15 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"context"
+	"errors"
+)
+
+// Process events need ETW on Windows, so Watch always scans.
+func watchConnector(ctx context.Context, ch chan<- ProcessEvent) error {
+	return errors.New("process connector not supported on windows")
+}
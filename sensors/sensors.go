@@ -0,0 +1,96 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sensors reads hardware monitoring sensors: temperatures, fan
+// speeds and voltages.
+package sensors
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Temperature struct {
+	Sensor   string  `json:"sensor"`   // chip or thermal zone type, such as "coretemp" or "acpitz"
+	Label    string  `json:"label"`    // such as "Package id 0" or "Core 1"
+	Current  float64 `json:"current"`  // degrees Celsius
+	High     float64 `json:"high"`     // 0 when unknown
+	Critical float64 `json:"critical"` // 0 when unknown
+}
+
+func (t Temperature) GoString() string {
+	s := []string{"Temperature{",
+			fmt.Sprintf("  Sensor   : %s", t.Sensor),
+			fmt.Sprintf("  Label    : %s", t.Label),
+			fmt.Sprintf("  Current  : %.1f°C", t.Current),
+			fmt.Sprintf("  High     : %.1f°C", t.High),
+			fmt.Sprintf("  Critical : %.1f°C", t.Critical),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+// Throttling reports whether the temperature reached the high threshold.
+func (t Temperature) Throttling() bool {
+	return t.High > 0 && t.Current >= t.High
+}
+
+//---------------------------------------------------------------------------------------
+
+type Fan struct {
+	Sensor string  `json:"sensor"`
+	Label  string  `json:"label"`
+	RPM    float64 `json:"rpm"`
+	Min    float64 `json:"min"` // 0 when unknown
+}
+
+func (f Fan) GoString() string {
+	s := []string{"Fan{",
+			fmt.Sprintf("  Sensor : %s", f.Sensor),
+			fmt.Sprintf("  Label  : %s", f.Label),
+			fmt.Sprintf("  RPM    : %.0f", f.RPM),
+			fmt.Sprintf("  Min    : %.0f", f.Min),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+//---------------------------------------------------------------------------------------
+
+type Voltage struct {
+	Sensor  string  `json:"sensor"`
+	Label   string  `json:"label"`
+	Current float64 `json:"current"` // volts
+	Min     float64 `json:"min"`     // 0 when unknown
+	Max     float64 `json:"max"`     // 0 when unknown
+}
+
+func (v Voltage) GoString() string {
+	s := []string{"Voltage{",
+			fmt.Sprintf("  Sensor  : %s", v.Sensor),
+			fmt.Sprintf("  Label   : %s", v.Label),
+			fmt.Sprintf("  Current : %.3fV", v.Current),
+			fmt.Sprintf("  Min     : %.3fV", v.Min),
+			fmt.Sprintf("  Max     : %.3fV", v.Max),
+			"}",
+	}
+	return strings.Join(s, "\n")
+}
+
+//---------------------------------------------------------------------------------------
+
+// Temperatures returns the readings of all temperature sensors.
+func Temperatures() ([]Temperature, error) {
+	return temperatures()
+}
+
+// Fans returns the speed of all fans.
+func Fans() ([]Fan, error) {
+	return fans()
+}
+
+// Voltages returns the readings of all voltage sensors.
+func Voltages() ([]Voltage, error) {
+	return voltages()
+}
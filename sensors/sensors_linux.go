@@ -0,0 +1,257 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sensors
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+// Sensors come from the hwmon class, plus the thermal zones that have no
+// hwmon device of their own.
+func temperatures() ([]Temperature, error) {
+	chips, err := hwmonChips()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Temperature
+	names := make(map[string]bool)
+	for _, c := range chips {
+		names[c.name] = true
+		for _, i := range c.indices("temp") {
+			ret = append(ret, Temperature{
+				Sensor   : c.name,
+				Label    : c.label("temp", i),
+				Current  : c.value("temp", i, "input") / 1000,
+				High     : c.value("temp", i, "max") / 1000,
+				Critical : c.value("temp", i, "crit") / 1000,
+			})
+		}
+	}
+
+	zones, err := thermalZones()
+	if err != nil {
+		return nil, err
+	}
+	for _, z := range zones {
+		// The thermal core registers hwmon devices named after the zone type.
+		if names[strings.Replace(z.Sensor, "-", "_", -1)] {
+			continue
+		}
+		ret = append(ret, z)
+	}
+	return ret, nil
+}
+
+func fans() ([]Fan, error) {
+	chips, err := hwmonChips()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Fan
+	for _, c := range chips {
+		for _, i := range c.indices("fan") {
+			ret = append(ret, Fan{
+				Sensor : c.name,
+				Label  : c.label("fan", i),
+				RPM    : c.value("fan", i, "input"),
+				Min    : c.value("fan", i, "min"),
+			})
+		}
+	}
+	return ret, nil
+}
+
+func voltages() ([]Voltage, error) {
+	chips, err := hwmonChips()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Voltage
+	for _, c := range chips {
+		// In millivolts.
+		for _, i := range c.indices("in") {
+			ret = append(ret, Voltage{
+				Sensor  : c.name,
+				Label   : c.label("in", i),
+				Current : c.value("in", i, "input") / 1000,
+				Min     : c.value("in", i, "min") / 1000,
+				Max     : c.value("in", i, "max") / 1000,
+			})
+		}
+	}
+	return ret, nil
+}
+
+//---------------------------------------------------------------------------------------
+
+// hwmonChip is a /sys/class/hwmon/hwmonN device.
+type hwmonChip struct {
+	dir   string
+	name  string
+	attrs map[string][]int // indices of the <kind><index>_input files by kind
+}
+
+var inputRe = regexp.MustCompile(`^(temp|fan|in)(\d+)_input$`)
+
+// hwmonChips lists the hwmon devices, none when the class does not exist.
+func hwmonChips() ([]*hwmonChip, error) {
+	dirs, err := classDevices("hwmon", "hwmon")
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*hwmonChip
+	for _, dir := range dirs {
+		// Drivers before Linux 3.11 put the attributes in the device directory.
+		if _, err := os.Stat(dir + "/name"); os.IsNotExist(err) {
+			dir += "/device"
+		}
+
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		c := &hwmonChip{
+			dir   : dir,
+			attrs : make(map[string][]int),
+		}
+		c.name, _ = linux.ReadString(dir + "/name")
+
+		for _, e := range entries {
+			m := inputRe.FindStringSubmatch(e.Name())
+			if m == nil {
+				continue
+			}
+			i, _ := strconv.Atoi(m[2])
+			c.attrs[m[1]] = append(c.attrs[m[1]], i)
+		}
+		for _, indices := range c.attrs {
+			sort.Ints(indices)
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+func (c *hwmonChip) indices(kind string) []int {
+	return c.attrs[kind]
+}
+
+func (c *hwmonChip) label(kind string, i int) string {
+	name := kind + strconv.Itoa(i)
+	if l, err := linux.ReadString(c.dir + "/" + name + "_label"); err == nil && l != "" {
+		return l
+	}
+	return name
+}
+
+// value reads <kind><i>_<item>, 0 when missing or unreadable.
+func (c *hwmonChip) value(kind string, i int, item string) float64 {
+	s, err := linux.ReadString(c.dir + "/" + kind + strconv.Itoa(i) + "_" + item)
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+//---------------------------------------------------------------------------------------
+
+// thermalZones reads /sys/class/thermal/thermal_zoneN. High is the hot trip
+// point, or the passive one where cooling starts when there is none.
+func thermalZones() ([]Temperature, error) {
+	dirs, err := classDevices("thermal", "thermal_zone")
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Temperature
+	for _, dir := range dirs {
+		milli, err := linux.ReadString(dir + "/temp")
+		if err != nil {
+			// Disabled zones fail with ENODATA or EAGAIN.
+			continue
+		}
+
+		t := Temperature{Label: dir[strings.LastIndexByte(dir, '/')+1:]}
+		t.Sensor, _ = linux.ReadString(dir + "/type")
+		t.Current = parseMilli(milli)
+
+		var passive float64
+		for i := 0; ; i++ {
+			prefix := dir + "/trip_point_" + strconv.Itoa(i)
+			typ, err := linux.ReadString(prefix + "_type")
+			if err != nil {
+				break
+			}
+			temp, err := linux.ReadString(prefix + "_temp")
+			if err != nil {
+				continue
+			}
+
+			switch typ {
+			case "critical":
+				t.Critical = parseMilli(temp)
+			case "hot":
+				t.High = parseMilli(temp)
+			case "passive":
+				if passive == 0 {
+					passive = parseMilli(temp)
+				}
+			}
+		}
+		if t.High == 0 {
+			t.High = passive
+		}
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
+
+// classDevices lists /sys/class/<class>/<prefix>N in numeric order.
+func classDevices(class, prefix string) ([]string, error) {
+	dir := linux.SysPath("class", class)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ids []int
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		if n, err := strconv.Atoi(e.Name()[len(prefix):]); err == nil {
+			ids = append(ids, n)
+		}
+	}
+	sort.Ints(ids)
+
+	var ret []string
+	for _, n := range ids {
+		ret = append(ret, dir + "/" + prefix + strconv.Itoa(n))
+	}
+	return ret, nil
+}
+
+func parseMilli(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v / 1000
+}
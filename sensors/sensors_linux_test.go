@@ -0,0 +1,118 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sensors
+
+import (
+	"testing"
+
+	"github.com/entuerto/sysmon/internal/linux/linuxtest"
+)
+
+var sensorTree = map[string]string{
+	"sys/class/hwmon/hwmon0/name":         "acpitz\n",
+	"sys/class/hwmon/hwmon0/temp1_input":  "27800\n",
+	"sys/class/hwmon/hwmon0/temp1_crit":   "119000\n",
+	"sys/class/hwmon/hwmon2/name":         "coretemp\n",
+	"sys/class/hwmon/hwmon2/temp1_input":  "45000\n",
+	"sys/class/hwmon/hwmon2/temp1_label":  "Package id 0\n",
+	"sys/class/hwmon/hwmon2/temp1_max":    "84000\n",
+	"sys/class/hwmon/hwmon2/temp1_crit":   "100000\n",
+	"sys/class/hwmon/hwmon2/temp10_input": "41000\n",
+	"sys/class/hwmon/hwmon2/temp10_label": "Core 8\n",
+	"sys/class/hwmon/hwmon2/temp2_input":  "43000\n",
+	"sys/class/hwmon/hwmon2/temp2_label":  "Core 0\n",
+	"sys/class/hwmon/hwmon10/device/name":        "it8728\n",
+	"sys/class/hwmon/hwmon10/device/fan1_input":  "1250\n",
+	"sys/class/hwmon/hwmon10/device/fan1_min":    "600\n",
+	"sys/class/hwmon/hwmon10/device/fan2_input":  "0\n",
+	"sys/class/hwmon/hwmon10/device/fan2_label":  "CPU_OPT\n",
+	"sys/class/hwmon/hwmon10/device/in0_input":   "1032\n",
+	"sys/class/hwmon/hwmon10/device/in0_min":     "900\n",
+	"sys/class/hwmon/hwmon10/device/in0_max":     "1250\n",
+	"sys/class/thermal/thermal_zone0/type":              "acpitz\n",
+	"sys/class/thermal/thermal_zone0/temp":              "27800\n",
+	"sys/class/thermal/thermal_zone1/type":              "x86_pkg_temp\n",
+	"sys/class/thermal/thermal_zone1/temp":              "46000\n",
+	"sys/class/thermal/thermal_zone1/trip_point_0_type": "passive\n",
+	"sys/class/thermal/thermal_zone1/trip_point_0_temp": "90000\n",
+	"sys/class/thermal/thermal_zone1/trip_point_1_type": "critical\n",
+	"sys/class/thermal/thermal_zone1/trip_point_1_temp": "105000\n",
+	"sys/class/thermal/cooling_device0/type":            "Processor\n",
+}
+
+func TestTemperatures(t *testing.T) {
+	defer linuxtest.FakeTree(t, sensorTree)()
+
+	temps, err := Temperatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Temperature{
+		{Sensor: "acpitz", Label: "temp1", Current: 27.8, Critical: 119},
+		{Sensor: "coretemp", Label: "Package id 0", Current: 45, High: 84, Critical: 100},
+		{Sensor: "coretemp", Label: "Core 0", Current: 43},
+		{Sensor: "coretemp", Label: "Core 8", Current: 41},
+		// acpitz zone skipped, already read through hwmon.
+		{Sensor: "x86_pkg_temp", Label: "thermal_zone1", Current: 46, High: 90, Critical: 105},
+	}
+	if len(temps) != len(want) {
+		t.Fatalf("got %d temperatures, want %d: %#v", len(temps), len(want), temps)
+	}
+	for i, w := range want {
+		if temps[i] != w {
+			t.Errorf("temperature %d: got %#v, want %#v", i, temps[i], w)
+		}
+	}
+}
+
+func TestFans(t *testing.T) {
+	defer linuxtest.FakeTree(t, sensorTree)()
+
+	fans, err := Fans()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Fan{
+		{Sensor: "it8728", Label: "fan1", RPM: 1250, Min: 600},
+		{Sensor: "it8728", Label: "CPU_OPT", RPM: 0},
+	}
+	if len(fans) != len(want) {
+		t.Fatalf("got %d fans, want %d: %#v", len(fans), len(want), fans)
+	}
+	for i, w := range want {
+		if fans[i] != w {
+			t.Errorf("fan %d: got %#v, want %#v", i, fans[i], w)
+		}
+	}
+}
+
+func TestVoltages(t *testing.T) {
+	defer linuxtest.FakeTree(t, sensorTree)()
+
+	volts, err := Voltages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Voltage{Sensor: "it8728", Label: "in0", Current: 1.032, Min: 0.9, Max: 1.25}
+	if len(volts) != 1 || volts[0] != want {
+		t.Errorf("got %#v, want %#v", volts, want)
+	}
+}
+
+func TestNoSensors(t *testing.T) {
+	defer linuxtest.FakeTree(t, map[string]string{"sys/devices/system/cpu/online": "0\n"})()
+
+	temps, err := Temperatures()
+	if err != nil || len(temps) != 0 {
+		t.Errorf("Temperatures() = %v, %v, want none", temps, err)
+	}
+	fans, err := Fans()
+	if err != nil || len(fans) != 0 {
+		t.Errorf("Fans() = %v, %v, want none", fans, err)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sensors
+
+import (
+	"errors"
+)
+
+// Windows only exposes sensors through vendor drivers.
+var errNotSupported = errors.New("sensors not supported on windows")
+
+func temperatures() ([]Temperature, error) {
+	return nil, errNotSupported
+}
+
+func fans() ([]Fan, error) {
+	return nil, errNotSupported
+}
+
+func voltages() ([]Voltage, error) {
+	return nil, errNotSupported
+}
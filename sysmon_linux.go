@@ -0,0 +1,27 @@
+// Copyright 2015 The sysmon Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sysmon
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/entuerto/sysmon/internal/linux"
+)
+
+func upTime() time.Duration {
+	s, err := linux.ReadString(linux.ProcPath("uptime"))
+	if err != nil {
+		return 0
+	}
+
+	// seconds since boot, idle seconds
+	secs, err := strconv.ParseFloat(strings.Fields(s)[0], 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
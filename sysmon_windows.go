@@ -5,6 +5,8 @@
 package sysmon
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/entuerto/sysmon/internal/win32"
@@ -13,4 +15,20 @@ import (
 func upTime() time.Duration {
 	d := win32.GetTickCount64()
 	return time.Duration(d) * time.Millisecond
-}
\ No newline at end of file
+}
+
+func limits() (*ResourceLimits, error) {
+	return nil, errors.New("resource limits not supported on windows")
+}
+
+func systemPressure(resource PressureResource) (*Pressure, error) {
+	return nil, errors.New("pressure stall information not supported on windows")
+}
+
+func cgroupPressure(cgroup string, resource PressureResource) (*Pressure, error) {
+	return nil, errors.New("pressure stall information not supported on windows")
+}
+
+func watchPressure(ctx context.Context, trigger PressureTrigger) (<-chan PressureEvent, error) {
+	return nil, errors.New("pressure stall information not supported on windows")
+}